@@ -0,0 +1,205 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/whoami/v1/whoami.proto
+
+package whoamiv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Whoami_Get_FullMethodName    = "/whoami.v1.Whoami/Get"
+	Whoami_Stream_FullMethodName = "/whoami.v1.Whoami/Stream"
+	Whoami_Echo_FullMethodName   = "/whoami.v1.Whoami/Echo"
+)
+
+// WhoamiClient is the client API for Whoami service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Whoami returns the server and the calling client details.
+type WhoamiClient interface {
+	// Get returns a single response.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	// Stream returns a response every interval until count responses are sent.
+	Stream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetResponse], error)
+	// Echo returns a response for every request of the stream.
+	Echo(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[GetRequest, GetResponse], error)
+}
+
+type whoamiClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWhoamiClient(cc grpc.ClientConnInterface) WhoamiClient {
+	return &whoamiClient{cc}
+}
+
+func (c *whoamiClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetResponse)
+	err := c.cc.Invoke(ctx, Whoami_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *whoamiClient) Stream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Whoami_ServiceDesc.Streams[0], Whoami_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, GetResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Whoami_StreamClient = grpc.ServerStreamingClient[GetResponse]
+
+func (c *whoamiClient) Echo(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[GetRequest, GetResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Whoami_ServiceDesc.Streams[1], Whoami_Echo_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetRequest, GetResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Whoami_EchoClient = grpc.BidiStreamingClient[GetRequest, GetResponse]
+
+// WhoamiServer is the server API for Whoami service.
+// All implementations must embed UnimplementedWhoamiServer
+// for forward compatibility.
+//
+// Whoami returns the server and the calling client details.
+type WhoamiServer interface {
+	// Get returns a single response.
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	// Stream returns a response every interval until count responses are sent.
+	Stream(*StreamRequest, grpc.ServerStreamingServer[GetResponse]) error
+	// Echo returns a response for every request of the stream.
+	Echo(grpc.BidiStreamingServer[GetRequest, GetResponse]) error
+	mustEmbedUnimplementedWhoamiServer()
+}
+
+// UnimplementedWhoamiServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWhoamiServer struct{}
+
+func (UnimplementedWhoamiServer) Get(context.Context, *GetRequest) (*GetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedWhoamiServer) Stream(*StreamRequest, grpc.ServerStreamingServer[GetResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedWhoamiServer) Echo(grpc.BidiStreamingServer[GetRequest, GetResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Echo not implemented")
+}
+func (UnimplementedWhoamiServer) mustEmbedUnimplementedWhoamiServer() {}
+func (UnimplementedWhoamiServer) testEmbeddedByValue()                {}
+
+// UnsafeWhoamiServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WhoamiServer will
+// result in compilation errors.
+type UnsafeWhoamiServer interface {
+	mustEmbedUnimplementedWhoamiServer()
+}
+
+func RegisterWhoamiServer(s grpc.ServiceRegistrar, srv WhoamiServer) {
+	// If the following call pancis, it indicates UnimplementedWhoamiServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Whoami_ServiceDesc, srv)
+}
+
+func _Whoami_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WhoamiServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Whoami_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WhoamiServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Whoami_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WhoamiServer).Stream(m, &grpc.GenericServerStream[StreamRequest, GetResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Whoami_StreamServer = grpc.ServerStreamingServer[GetResponse]
+
+func _Whoami_Echo_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WhoamiServer).Echo(&grpc.GenericServerStream[GetRequest, GetResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Whoami_EchoServer = grpc.BidiStreamingServer[GetRequest, GetResponse]
+
+// Whoami_ServiceDesc is the grpc.ServiceDesc for Whoami service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Whoami_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "whoami.v1.Whoami",
+	HandlerType: (*WhoamiServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _Whoami_Get_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _Whoami_Stream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Echo",
+			Handler:       _Whoami_Echo_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/whoami/v1/whoami.proto",
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"flag"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -11,6 +15,16 @@ import (
 type Config struct {
 	ServerHost         string
 	ServerPort         string
+	TLSServerPort      string
+	AdminAddr          string
+	GRPCAddr           string
+	TCPEchoAddr        string
+	UDPEchoAddr        string
+	ProxyProtocol      bool
+	ProxyTrustedCIDRs  []netip.Prefix
+	TrustedProxies     []netip.Prefix
+	H2C                bool
+	HTTP3              bool
 	LogFormatter       string // Possible values: fmt, json.
 	LogLevel           string // Possible values: error, warn, info, debug.
 	AccessLogEnabled   bool
@@ -20,7 +34,32 @@ type Config struct {
 	WriteTimeout       time.Duration
 	TLSCrtFile         string
 	TLSKeyFile         string
+	TLSCertPairs       []string // List of "cert:key" file pairs.
+	TLSCertDir         string
 	TLSCAFile          string
+	TLSClientAuth      string // Possible values: none, request, require, verify-if-given, require-and-verify.
+	TLSMinVersion      string // Possible values: 1.0, 1.1, 1.2, 1.3.
+	TLSMaxVersion      string // Possible values: 1.0, 1.1, 1.2, 1.3.
+	TLSCipherSuites    []string
+	TLSCurves          []string
+	TLSReloadInterval  time.Duration
+	TLSSelfSigned      bool
+	TLSSelfSignedSANs  []string
+	TLSSelfSignedCA    string
+	MaxBodySize        int64
+	HostInfoRefresh    time.Duration
+	StartupDelay       time.Duration
+	ShutdownDelay      time.Duration
+	EnvEnabled         bool
+	EnvAllow           []string
+	EnvDeny            []string
+	EnvRedact          []string
+	RedactHeaders      []string
+	K8sPodInfoDir      string
+	K8sNodeNameEnv     string
+	K8sPodIPEnv        string
+	K8sZoneEnv         string
+	FaultDefaults      url.Values
 }
 
 // NewConfig creates a new Config object with default values.
@@ -35,11 +74,26 @@ func NewConfig() (*Config, error) {
 
 	cfg := &Config{}
 
-	var accessLogSkipPaths string
+	var accessLogSkipPaths, proxyTrustedCIDRs, trustedProxies string
 	var readTimeout, readHeaderTimeout, writeTimeout string
+	var tlsCertPairs string
+	var tlsCipherSuites, tlsCurves, tlsReloadInterval, tlsSelfSignedSANs string
+	var maxBodySize, hostInfoRefreshInterval, startupDelay, shutdownDelay string
+	var envAllow, envDeny, envRedact, redactHeaders string
+	var faultDefaults string
 
 	flag.StringVar(&cfg.ServerHost, "host", getEnv("WHOAMI_HOST", "0.0.0.0"), "Web server host address")
 	flag.StringVar(&cfg.ServerPort, "port", getEnv("WHOAMI_PORT", "8080"), "Web server port number")
+	flag.StringVar(&cfg.TLSServerPort, "tls-port", getEnv("WHOAMI_TLS_PORT", ""), "Web server HTTPS port number, if set plain HTTP is served on port simultaneously")
+	flag.StringVar(&cfg.AdminAddr, "admin-addr", getEnv("WHOAMI_ADMIN_ADDR", ""), "Admin server address for metrics, health, fault rules and pprof endpoints, if set they are not served on the public port. The fault rules and pprof endpoints are only served on it")
+	flag.StringVar(&cfg.GRPCAddr, "grpc-addr", getEnv("WHOAMI_GRPC_ADDR", ""), "gRPC server address, the gRPC server is disabled if not set")
+	flag.StringVar(&cfg.TCPEchoAddr, "tcp-echo-addr", getEnv("WHOAMI_TCP_ECHO_ADDR", ""), "TCP echo server address, the TCP echo server is disabled if not set")
+	flag.StringVar(&cfg.UDPEchoAddr, "udp-echo-addr", getEnv("WHOAMI_UDP_ECHO_ADDR", ""), "UDP echo server address, the UDP echo server is disabled if not set")
+	flag.BoolVar(&cfg.ProxyProtocol, "proxy-protocol", getEnv("WHOAMI_PROXY_PROTOCOL", "false") == "true", "Accept PROXY protocol v1 and v2 headers on the HTTP, HTTPS, gRPC and TCP echo listeners")
+	flag.StringVar(&proxyTrustedCIDRs, "proxy-protocol-trusted-cidrs", getEnv("WHOAMI_PROXY_PROTOCOL_TRUSTED_CIDRS", ""), "Comma separated list of addresses and CIDRs allowed to send the PROXY protocol header, any source if empty")
+	flag.StringVar(&trustedProxies, "trusted-proxies", getEnv("WHOAMI_TRUSTED_PROXIES", ""), "Comma separated list of addresses and CIDRs of the proxies trusted to set the forwarding headers")
+	flag.BoolVar(&cfg.H2C, "h2c", getEnv("WHOAMI_H2C", "false") == "true", "Enable HTTP/2 over cleartext (h2c) on the plain HTTP port")
+	flag.BoolVar(&cfg.HTTP3, "http3", getEnv("WHOAMI_HTTP3", "false") == "true", "Enable HTTP/3 over QUIC on the UDP port of the HTTPS server, requires TLS")
 	flag.StringVar(&cfg.LogFormatter, "log-formatter", getEnv("WHOAMI_LOG_FORMATTER", "json"), "Log formatter: 'fmt' or 'json'")
 	flag.StringVar(&cfg.LogLevel, "log-level", getEnv("WHOAMI_LOG_LEVEL", "info"), "Log level: 'error', 'warn', 'error', 'debug'")
 	flag.BoolVar(&cfg.AccessLogEnabled, "access-log", getEnv("WHOAMI_ACCESS_LOG", "false") == "true", "Enable access log")
@@ -49,7 +103,32 @@ func NewConfig() (*Config, error) {
 	flag.StringVar(&writeTimeout, "write-timeout", getEnv("WHOAMI_WRITE_TIMEOUT", "0s"), "Web server write timeout")
 	flag.StringVar(&cfg.TLSCrtFile, "tls-cert", getEnv("WHOAMI_TLS_CERT_FILE", ""), "TLS certificate file")
 	flag.StringVar(&cfg.TLSKeyFile, "tls-key", getEnv("WHOAMI_TLS_KEY_FILE", ""), "TLS private key file")
+	flag.StringVar(&tlsCertPairs, "tls-certs", getEnv("WHOAMI_TLS_CERTS", ""), "Comma separated list of 'cert:key' TLS certificate and private key file pairs selected by SNI")
+	flag.StringVar(&cfg.TLSCertDir, "tls-cert-dir", getEnv("WHOAMI_TLS_CERT_DIR", ""), "Directory with '<name>.crt' and '<name>.key' TLS certificate and private key files selected by SNI")
 	flag.StringVar(&cfg.TLSCAFile, "tls-ca", getEnv("WHOAMI_TLS_CA_FILE", ""), "TLS CA certificate file for mTLS authentication")
+	flag.StringVar(&cfg.TLSClientAuth, "tls-client-auth", getEnv("WHOAMI_TLS_CLIENT_AUTH", ""), "TLS client auth mode: 'none', 'request', 'require', 'verify-if-given', 'require-and-verify' (default 'require-and-verify' if tls-ca is set)")
+	flag.StringVar(&cfg.TLSMinVersion, "tls-min-version", getEnv("WHOAMI_TLS_MIN_VERSION", "1.2"), "TLS minimum version: '1.0', '1.1', '1.2', '1.3'")
+	flag.StringVar(&cfg.TLSMaxVersion, "tls-max-version", getEnv("WHOAMI_TLS_MAX_VERSION", ""), "TLS maximum version: '1.0', '1.1', '1.2', '1.3'")
+	flag.StringVar(&tlsCipherSuites, "tls-cipher-suites", getEnv("WHOAMI_TLS_CIPHER_SUITES", ""), "Comma separated list of TLS 1.0-1.2 cipher suite names")
+	flag.StringVar(&tlsCurves, "tls-curves", getEnv("WHOAMI_TLS_CURVES", ""), "Comma separated list of TLS curves: 'X25519', 'P256', 'P384', 'P521'")
+	flag.StringVar(&tlsReloadInterval, "tls-reload-interval", getEnv("WHOAMI_TLS_RELOAD_INTERVAL", "10s"), "TLS certificate files change check interval, 0s to disable reloading")
+	flag.BoolVar(&cfg.TLSSelfSigned, "tls-self-signed", getEnv("WHOAMI_TLS_SELF_SIGNED", "false") == "true", "Enable TLS with a self-signed certificate generated at startup")
+	flag.StringVar(&tlsSelfSignedSANs, "tls-self-signed-sans", getEnv("WHOAMI_TLS_SELF_SIGNED_SANS", ""), "Comma separated list of self-signed certificate DNS names and IP addresses (default hostname and local IPs)")
+	flag.StringVar(&cfg.TLSSelfSignedCA, "tls-self-signed-ca-out", getEnv("WHOAMI_TLS_SELF_SIGNED_CA_OUT", ""), "File to write the self-signed CA certificate to")
+	flag.StringVar(&maxBodySize, "max-body-size", getEnv("WHOAMI_MAX_BODY_SIZE", "1048576"), "Maximum request body size in bytes to echo in the response, 0 to disable")
+	flag.StringVar(&hostInfoRefreshInterval, "host-info-refresh-interval", getEnv("WHOAMI_HOST_INFO_REFRESH_INTERVAL", "30s"), "Hostname, network interfaces and environment refresh interval, 0s to disable periodic refreshes")
+	flag.BoolVar(&cfg.EnvEnabled, "env", getEnv("WHOAMI_ENV", "true") == "true", "Enable the environment variables report of the 'env' query parameter")
+	flag.StringVar(&envAllow, "env-allow", getEnv("WHOAMI_ENV_ALLOW", ""), "Comma separated list of glob patterns of the reported environment variable names, all if empty")
+	flag.StringVar(&envDeny, "env-deny", getEnv("WHOAMI_ENV_DENY", ""), "Comma separated list of glob patterns of the hidden environment variable names, takes precedence over env-allow")
+	flag.StringVar(&envRedact, "env-redact", getEnv("WHOAMI_ENV_REDACT", "*TOKEN*,*PASSWORD*,*KEY*,*SECRET*"), "Comma separated list of glob patterns of the environment variable names with redacted values")
+	flag.StringVar(&redactHeaders, "redact-headers", getEnv("WHOAMI_REDACT_HEADERS", "Authorization,Proxy-Authorization,Cookie,Set-Cookie"), "Comma separated list of request header names with redacted values")
+	flag.StringVar(&cfg.K8sPodInfoDir, "k8s-podinfo-dir", getEnv("WHOAMI_K8S_PODINFO_DIR", "/etc/podinfo"), "Directory of the Kubernetes downward API 'labels' and 'annotations' files")
+	flag.StringVar(&cfg.K8sNodeNameEnv, "k8s-node-name-env", getEnv("WHOAMI_K8S_NODE_NAME_ENV", "NODE_NAME"), "Environment variable holding the Kubernetes node name")
+	flag.StringVar(&cfg.K8sPodIPEnv, "k8s-pod-ip-env", getEnv("WHOAMI_K8S_POD_IP_ENV", "POD_IP"), "Environment variable holding the Kubernetes pod IP")
+	flag.StringVar(&cfg.K8sZoneEnv, "k8s-zone-env", getEnv("WHOAMI_K8S_ZONE_ENV", "NODE_ZONE"), "Environment variable holding the Kubernetes node zone")
+	flag.StringVar(&faultDefaults, "fault-defaults", getEnv("WHOAMI_FAULT_DEFAULTS", ""), "Server-side fault injection parameters in query string format (ex. 'error_rate=0.1&error_code=503')")
+	flag.StringVar(&startupDelay, "startup-delay", getEnv("WHOAMI_STARTUP_DELAY", "0s"), "Delay after the server start before the startup and readiness probes pass")
+	flag.StringVar(&shutdownDelay, "shutdown-delay", getEnv("WHOAMI_SHUTDOWN_DELAY", "0s"), "Delay between the readiness probe failure and the listeners shutdown on termination")
 
 	flag.Parse()
 
@@ -57,6 +136,22 @@ func NewConfig() (*Config, error) {
 		cfg.AccessLogSkipPaths = strings.Split(accessLogSkipPaths, ",")
 	}
 
+	if tlsCertPairs != "" {
+		cfg.TLSCertPairs = strings.Split(tlsCertPairs, ",")
+	}
+
+	if tlsCipherSuites != "" {
+		cfg.TLSCipherSuites = strings.Split(tlsCipherSuites, ",")
+	}
+
+	if tlsCurves != "" {
+		cfg.TLSCurves = strings.Split(tlsCurves, ",")
+	}
+
+	if tlsSelfSignedSANs != "" {
+		cfg.TLSSelfSignedSANs = strings.Split(tlsSelfSignedSANs, ",")
+	}
+
 	var err error
 
 	cfg.ReadTimeout, err = time.ParseDuration(readTimeout)
@@ -74,9 +169,111 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("time.ParseDuration: %w", err)
 	}
 
+	cfg.TLSReloadInterval, err = time.ParseDuration(tlsReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("time.ParseDuration: %w", err)
+	}
+
+	cfg.MaxBodySize, err = strconv.ParseInt(maxBodySize, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("strconv.ParseInt: %w", err)
+	}
+
+	cfg.HostInfoRefresh, err = time.ParseDuration(hostInfoRefreshInterval)
+	if err != nil {
+		return nil, fmt.Errorf("time.ParseDuration: %w", err)
+	}
+
+	cfg.StartupDelay, err = time.ParseDuration(startupDelay)
+	if err != nil {
+		return nil, fmt.Errorf("time.ParseDuration: %w", err)
+	}
+
+	cfg.ShutdownDelay, err = time.ParseDuration(shutdownDelay)
+	if err != nil {
+		return nil, fmt.Errorf("time.ParseDuration: %w", err)
+	}
+
+	for _, list := range []struct {
+		value  string
+		target *[]string
+	}{
+		{envAllow, &cfg.EnvAllow},
+		{envDeny, &cfg.EnvDeny},
+		{envRedact, &cfg.EnvRedact},
+	} {
+		if list.value == "" {
+			continue
+		}
+
+		for _, pattern := range strings.Split(list.value, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
+			}
+
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("path.Match: %q: %w", pattern, err)
+			}
+
+			*list.target = append(*list.target, pattern)
+		}
+	}
+
+	cfg.FaultDefaults, err = url.ParseQuery(faultDefaults)
+	if err != nil {
+		return nil, fmt.Errorf("url.ParseQuery: %w", err)
+	}
+
+	for _, name := range strings.Split(redactHeaders, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.RedactHeaders = append(cfg.RedactHeaders, name)
+		}
+	}
+
+	if proxyTrustedCIDRs != "" {
+		for _, s := range strings.Split(proxyTrustedCIDRs, ",") {
+			prefix, err := parsePrefix(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("parsePrefix: %w", err)
+			}
+
+			cfg.ProxyTrustedCIDRs = append(cfg.ProxyTrustedCIDRs, prefix)
+		}
+	}
+
+	if trustedProxies != "" {
+		for _, s := range strings.Split(trustedProxies, ",") {
+			prefix, err := parsePrefix(strings.TrimSpace(s))
+			if err != nil {
+				return nil, fmt.Errorf("parsePrefix: %w", err)
+			}
+
+			cfg.TrustedProxies = append(cfg.TrustedProxies, prefix)
+		}
+	}
+
 	return cfg, nil
 }
 
+// parsePrefix parses a CIDR or a single IP address as a network prefix.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("netip.ParsePrefix: %w", err)
+		}
+
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("netip.ParseAddr: %w", err)
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
 // getEnv gets the value of an environment variable specified by the key.
 //
 // It takes two parameters:
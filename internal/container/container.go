@@ -0,0 +1,288 @@
+// Package container detects the container the process runs in and its cgroup resource limits.
+package container
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	procCgroupFile    = "/proc/self/cgroup"
+	procMountInfoFile = "/proc/self/mountinfo"
+)
+
+// unlimitedMemory is the threshold above which a cgroup v1 memory limit means no limit.
+// The kernel reports the maximum page-aligned value, which varies with the page size.
+const unlimitedMemory = 1 << 62
+
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// Info holds the container ID and the cgroup resource limits of the process.
+type Info struct {
+	// ID is the container ID, empty if it cannot be found.
+	ID string
+	// Cgroup is nil if the cgroup hierarchy cannot be found.
+	Cgroup *Cgroup
+}
+
+// Cgroup holds the cgroup of the process and its resource limits.
+type Cgroup struct {
+	Version int    // 1 or 2.
+	Path    string // The path of the process cgroup in the hierarchy.
+
+	// CPUQuota is the CPU limit in cores, 0 if unlimited.
+	CPUQuota float64
+	// MemoryLimit is the memory limit in bytes, 0 if unlimited.
+	MemoryLimit int64
+
+	cpuDir    string
+	memoryDir string
+}
+
+// mountInfo is an entry of the /proc/self/mountinfo file.
+type mountInfo struct {
+	root         string
+	mountPoint   string
+	fsType       string
+	superOptions []string
+}
+
+// Detect returns the container ID and the cgroup resource limits of the process.
+//
+// The container ID is the first 64 hex characters long ID found in the /proc/self/cgroup
+// paths, or in the /proc/self/mountinfo container paths (ex. /var/lib/docker/containers/<id>/hostname).
+//
+// Returns:
+// - *Info: The container details.
+func Detect() *Info {
+	cgroupData, _ := os.ReadFile(procCgroupFile)
+	mountData, _ := os.ReadFile(procMountInfoFile)
+
+	info := &Info{
+		ID: findContainerID(string(cgroupData), string(mountData)),
+	}
+
+	cgroup, err := newCgroup(string(cgroupData), parseMountInfo(string(mountData)))
+	if err == nil {
+		info.Cgroup = cgroup
+	}
+
+	return info
+}
+
+// MemoryUsage returns the current memory usage of the cgroup in bytes.
+func (c *Cgroup) MemoryUsage() (int64, error) {
+	name := "memory.current"
+	if c.Version == 1 {
+		name = "memory.usage_in_bytes"
+	}
+
+	return readInt(filepath.Join(c.memoryDir, name))
+}
+
+// newCgroup locates the cgroup directories of the process and reads its CPU and memory limits.
+// The cgroup v1 hierarchy is used if its memory or cpu controller is mounted, so hybrid
+// setups are reported from the hierarchy holding the controllers.
+func newCgroup(cgroupData string, mounts []mountInfo) (*Cgroup, error) {
+	v1Paths := make(map[string]string)
+	var v2Path string
+	var hasV2Path bool
+
+	for _, line := range strings.Split(strings.TrimSpace(cgroupData), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if parts[0] == "0" && parts[1] == "" {
+			v2Path, hasV2Path = parts[2], true
+
+			continue
+		}
+
+		for _, controller := range strings.Split(parts[1], ",") {
+			v1Paths[controller] = parts[2]
+		}
+	}
+
+	cpuDir, cpuOK := v1Dir(mounts, v1Paths, "cpu")
+	memoryDir, memoryOK := v1Dir(mounts, v1Paths, "memory")
+
+	if cpuOK || memoryOK {
+		cgroup := &Cgroup{
+			Version:   1,
+			Path:      valueOr(v1Paths["memory"], v1Paths["cpu"]),
+			cpuDir:    cpuDir,
+			memoryDir: memoryDir,
+		}
+
+		quota, errQuota := readInt(filepath.Join(cpuDir, "cpu.cfs_quota_us"))
+		period, errPeriod := readInt(filepath.Join(cpuDir, "cpu.cfs_period_us"))
+
+		if errQuota == nil && errPeriod == nil && quota > 0 && period > 0 {
+			cgroup.CPUQuota = float64(quota) / float64(period)
+		}
+
+		if limit, err := readInt(filepath.Join(memoryDir, "memory.limit_in_bytes")); err == nil && limit < unlimitedMemory {
+			cgroup.MemoryLimit = limit
+		}
+
+		return cgroup, nil
+	}
+
+	if !hasV2Path {
+		return nil, errors.New("cgroup not found")
+	}
+
+	for _, m := range mounts {
+		if m.fsType != "cgroup2" {
+			continue
+		}
+
+		dir := cgroupDir(m, v2Path)
+
+		cgroup := &Cgroup{
+			Version:   2,
+			Path:      v2Path,
+			cpuDir:    dir,
+			memoryDir: dir,
+		}
+
+		if data, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+			fields := strings.Fields(string(data))
+			if len(fields) == 2 && fields[0] != "max" {
+				quota, errQuota := strconv.ParseInt(fields[0], 10, 64)
+				period, errPeriod := strconv.ParseInt(fields[1], 10, 64)
+
+				if errQuota == nil && errPeriod == nil && period > 0 {
+					cgroup.CPUQuota = float64(quota) / float64(period)
+				}
+			}
+		}
+
+		if limit, err := readInt(filepath.Join(dir, "memory.max")); err == nil {
+			cgroup.MemoryLimit = limit
+		}
+
+		return cgroup, nil
+	}
+
+	return nil, errors.New("cgroup2 mount not found")
+}
+
+// v1Dir returns the directory of the process cgroup of the cgroup v1 controller.
+func v1Dir(mounts []mountInfo, paths map[string]string, controller string) (string, bool) {
+	path, ok := paths[controller]
+	if !ok {
+		return "", false
+	}
+
+	for _, m := range mounts {
+		if m.fsType != "cgroup" {
+			continue
+		}
+
+		for _, opt := range m.superOptions {
+			if opt == controller {
+				return cgroupDir(m, path), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// cgroupDir returns the directory of the cgroup path under the mount. The mount point itself is
+// returned if the path is outside of the mount root, as seen from a cgroup namespace.
+func cgroupDir(m mountInfo, path string) string {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return m.mountPoint
+	}
+
+	dir := filepath.Join(m.mountPoint, rel)
+
+	// Only the process cgroup may be mounted at the mount point, as done by some container runtimes.
+	if _, err := os.Stat(dir); err != nil {
+		return m.mountPoint
+	}
+
+	return dir
+}
+
+// parseMountInfo parses the entries of the /proc/self/mountinfo file.
+func parseMountInfo(data string) []mountInfo {
+	var mounts []mountInfo
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+
+		// The optional fields end with a single hyphen followed by the filesystem type, source and super options.
+		sep := -1
+		for i, f := range fields {
+			if f == "-" {
+				sep = i
+
+				break
+			}
+		}
+
+		if sep < 5 || len(fields) < sep+4 {
+			continue
+		}
+
+		mounts = append(mounts, mountInfo{
+			root:         fields[3],
+			mountPoint:   fields[4],
+			fsType:       fields[sep+1],
+			superOptions: strings.Split(fields[sep+3], ","),
+		})
+	}
+
+	return mounts
+}
+
+// findContainerID returns the first container ID found in the cgroup paths or the container mounts.
+func findContainerID(cgroupData, mountData string) string {
+	if id := containerIDRegexp.FindString(cgroupData); id != "" {
+		return id
+	}
+
+	for _, line := range strings.Split(mountData, "\n") {
+		if _, after, ok := strings.Cut(line, "/containers/"); ok {
+			if id := containerIDRegexp.FindString(after); id != "" && strings.HasPrefix(after, id) {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
+
+// readInt reads a file holding a single integer.
+func readInt(name string) (int64, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return 0, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("strconv.ParseInt: %w", err)
+	}
+
+	return v, nil
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
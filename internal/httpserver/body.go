@@ -0,0 +1,163 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Request body encodings.
+const (
+	bodyEncodingText   = "text"
+	bodyEncodingBase64 = "base64"
+)
+
+type bodyInfo struct {
+	Size                int64               `json:"size"`
+	Truncated           bool                `json:"truncated,omitempty"`
+	ContentType         string              `json:"content_type,omitempty"`
+	DetectedContentType string              `json:"detected_content_type"`
+	Encoding            string              `json:"encoding"`
+	Raw                 string              `json:"raw"`
+	Form                map[string][]string `json:"form,omitempty"`
+	Files               []bodyFile          `json:"files,omitempty"`
+	JSON                any                 `json:"json,omitempty"`
+	Error               string              `json:"error,omitempty"`
+}
+
+type bodyFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// getBodyInfo reads up to maxSize bytes of the request body and describes its content.
+//
+// Parameters:
+// - r: The HTTP request.
+// - maxSize: The maximum number of body bytes to read. Zero or negative value disables reading.
+//
+// Returns:
+// - *bodyInfo: The request body description or nil if the body is empty or disabled.
+// - error: An error if the body could not be read.
+func getBodyInfo(r *http.Request, maxSize int64) (*bodyInfo, error) {
+	if maxSize <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return nil, nil //nolint:nilnil
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll: %w", err)
+	}
+
+	if len(raw) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	body := &bodyInfo{
+		ContentType: r.Header.Get("Content-Type"),
+	}
+
+	if int64(len(raw)) > maxSize {
+		raw = raw[:maxSize]
+		body.Truncated = true
+	}
+
+	body.Size = int64(len(raw))
+	body.DetectedContentType = http.DetectContentType(raw)
+
+	if utf8.Valid(raw) {
+		body.Encoding = bodyEncodingText
+		body.Raw = string(raw)
+	} else {
+		body.Encoding = bodyEncodingBase64
+		body.Raw = base64.StdEncoding.EncodeToString(raw)
+	}
+
+	// Partial payloads cannot be parsed reliably.
+	if body.Truncated {
+		return body, nil
+	}
+
+	if err := body.parse(raw); err != nil {
+		body.Error = err.Error()
+	}
+
+	return body, nil
+}
+
+// parse decodes the structured body content according to the declared content type.
+func (b *bodyInfo) parse(raw []byte) error {
+	if b.ContentType == "" {
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(b.ContentType)
+	if err != nil {
+		return fmt.Errorf("mime.ParseMediaType: %w", err)
+	}
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return fmt.Errorf("url.ParseQuery: %w", err)
+		}
+
+		b.Form = form
+
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return b.parseMultipart(raw, params["boundary"])
+
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if err := json.Unmarshal(raw, &b.JSON); err != nil {
+			return fmt.Errorf("json.Unmarshal: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *bodyInfo) parseMultipart(raw []byte, boundary string) error {
+	if boundary == "" {
+		return errors.New("multipart boundary is missing")
+	}
+
+	form, err := multipart.NewReader(bytes.NewReader(raw), boundary).ReadForm(int64(len(raw)))
+	if err != nil {
+		return fmt.Errorf("multipart.ReadForm: %w", err)
+	}
+	defer form.RemoveAll() //nolint:errcheck
+
+	if len(form.Value) > 0 {
+		b.Form = form.Value
+	}
+
+	for field, files := range form.File {
+		for _, f := range files {
+			b.Files = append(b.Files, bodyFile{
+				Field:       field,
+				Filename:    f.Filename,
+				Size:        f.Size,
+				ContentType: f.Header.Get("Content-Type"),
+			})
+		}
+	}
+
+	sort.Slice(b.Files, func(i, j int) bool {
+		return b.Files[i].Field < b.Files[j].Field
+	})
+
+	return nil
+}
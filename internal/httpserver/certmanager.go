@@ -0,0 +1,389 @@
+package httpserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Certificate selection results.
+const (
+	certMatchExact    = "exact"
+	certMatchWildcard = "wildcard"
+	certMatchDefault  = "default"
+)
+
+// selfSignedCertName is the name of the in-memory self-signed certificate.
+const selfSignedCertName = "self-signed"
+
+var (
+	promCertExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "whoami",
+			Subsystem: "tls",
+			Name:      "certificate_expiry_timestamp_seconds",
+			Help:      "Expiry time of the loaded TLS certificates in unix seconds.",
+		}, []string{"type", "name"})
+
+	promCertReloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "whoami",
+			Subsystem: "tls",
+			Name:      "reloads_total",
+			Help:      "Total number of TLS certificate reloads.",
+		}, []string{"result"})
+)
+
+// certKeyPair is a pair of TLS certificate and private key files.
+type certKeyPair struct {
+	certFile string
+	keyFile  string
+}
+
+// parseCertKeyPairs parses the list of "cert:key" file pairs.
+func parseCertKeyPairs(pairs []string) ([]certKeyPair, error) {
+	result := make([]certKeyPair, 0, len(pairs))
+
+	for _, pair := range pairs {
+		certFile, keyFile, ok := strings.Cut(pair, ":")
+		if !ok || certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("invalid certificate and key pair: %q, expected format is cert:key", pair)
+		}
+
+		result = append(result, certKeyPair{certFile: certFile, keyFile: keyFile})
+	}
+
+	return result, nil
+}
+
+// namedCertificate is a loaded certificate with the name of its source.
+type namedCertificate struct {
+	name string
+	cert *tls.Certificate
+}
+
+type servedCertificateInfo struct {
+	Name     string    `json:"name"`
+	Match    string    `json:"match"`
+	Subject  string    `json:"subject"`
+	DNSNames []string  `json:"dns_names,omitempty"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// certManager keeps the TLS certificates and the client CA pool loaded from files,
+// selects the certificate by the SNI server name and reloads the files atomically
+// when their content changes.
+type certManager struct {
+	pairs    []certKeyPair
+	certDir  string
+	caFile   string
+	interval time.Duration
+
+	// static is the in-memory certificate served as the default one.
+	static *namedCertificate
+
+	// certs is the list of loaded certificates, the first one is the default.
+	certs  atomic.Pointer[[]*namedCertificate]
+	caPool atomic.Pointer[x509.CertPool]
+
+	// checksum is the digest of the last loaded files content.
+	checksum [sha256.Size]byte
+}
+
+// newCertManager creates a certManager and loads the certificate files.
+//
+// Parameters:
+// - pairs: The list of certificate and key files. The first pair is the default certificate.
+// - certDir: The optional directory with <name>.crt and <name>.key certificate files.
+// - caFile: The optional CA certificate file used to verify client certificates.
+// - interval: The interval of files change checks. Zero value disables reloading.
+//
+// Returns:
+// - *certManager: The certificate manager.
+// - error: An error if the files could not be loaded.
+func newCertManager(pairs []certKeyPair, certDir, caFile string, interval time.Duration) (*certManager, error) {
+	m := &certManager{
+		pairs:    pairs,
+		certDir:  certDir,
+		caFile:   caFile,
+		interval: interval,
+	}
+
+	if _, err := m.reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// tlsConfig returns a copy of the base config serving the managed certificates and client CA pool.
+func (m *certManager) tlsConfig(base *tls.Config) *tls.Config {
+	cfg := base.Clone()
+	cfg.GetCertificate = m.getCertificate
+
+	// The per-client config is cloned from this one, so the ALPN protocols which
+	// http.Server adds to its own copy of the config have to be set here.
+	if len(cfg.NextProtos) == 0 {
+		cfg.NextProtos = []string{"h2", "http/1.1"}
+	}
+
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := cfg.Clone()
+		c.GetConfigForClient = nil
+
+		if pool := m.caPool.Load(); pool != nil {
+			c.ClientCAs = pool
+		}
+
+		return c, nil
+	}
+
+	return cfg
+}
+
+// getCertificate selects the certificate by the SNI server name and records
+// the selection in the connection context.
+func (m *certManager) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certs := m.certs.Load()
+	if certs == nil || len(*certs) == 0 {
+		return nil, errors.New("no TLS certificate loaded")
+	}
+
+	nc, match := selectCertificate(*certs, hello.ServerName)
+
+	if conn := getConnInfo(hello.Context()); conn != nil {
+		conn.servedCertificate.Store(&servedCertificateInfo{
+			Name:     nc.name,
+			Match:    match,
+			Subject:  nc.cert.Leaf.Subject.String(),
+			DNSNames: nc.cert.Leaf.DNSNames,
+			NotAfter: nc.cert.Leaf.NotAfter.UTC(),
+		})
+	}
+
+	return nc.cert, nil
+}
+
+// selectCertificate returns the certificate matching the server name exactly,
+// then by a wildcard name and falls back to the first certificate otherwise.
+func selectCertificate(certs []*namedCertificate, serverName string) (*namedCertificate, string) {
+	serverName = strings.ToLower(strings.TrimSuffix(serverName, "."))
+
+	if serverName != "" {
+		wildcard := ""
+		if _, domain, ok := strings.Cut(serverName, "."); ok {
+			wildcard = "*." + domain
+		}
+
+		var wildcardMatch *namedCertificate
+
+		for _, nc := range certs {
+			for _, name := range nc.cert.Leaf.DNSNames {
+				name = strings.ToLower(name)
+
+				if name == serverName {
+					return nc, certMatchExact
+				}
+
+				if wildcardMatch == nil && wildcard != "" && name == wildcard {
+					wildcardMatch = nc
+				}
+			}
+		}
+
+		if wildcardMatch != nil {
+			return wildcardMatch, certMatchWildcard
+		}
+	}
+
+	return certs[0], certMatchDefault
+}
+
+// Run checks the files for changes with the configured interval until the stop channel is closed.
+func (m *certManager) Run(stop <-chan struct{}) {
+	if m.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			reloaded, err := m.reload()
+			if err != nil {
+				promCertReloads.WithLabelValues("failure").Inc()
+				slog.Error(fmt.Sprintf("TLS certificates reload failed: %v", err))
+
+				continue
+			}
+
+			if reloaded {
+				promCertReloads.WithLabelValues("success").Inc()
+				slog.Info("TLS certificates reloaded")
+			}
+		}
+	}
+}
+
+// setCertificate sets the in-memory certificate served as the default one.
+// It must be called before Run.
+func (m *certManager) setCertificate(cert *tls.Certificate) {
+	m.static = &namedCertificate{name: selfSignedCertName, cert: cert}
+
+	certs := []*namedCertificate{m.static}
+	if loaded := m.certs.Load(); loaded != nil {
+		certs = append(certs, *loaded...)
+	}
+
+	m.certs.Store(&certs)
+
+	promCertExpiry.WithLabelValues("serving", selfSignedCertName).Set(float64(cert.Leaf.NotAfter.Unix()))
+}
+
+// reload loads the files if their content has changed since the last load.
+// The current certificates are kept if any of the files is invalid.
+func (m *certManager) reload() (bool, error) {
+	pairs, err := m.listPairs()
+	if err != nil {
+		return false, err
+	}
+
+	var contents [][]byte
+
+	for _, pair := range pairs {
+		certPEM, err := os.ReadFile(pair.certFile)
+		if err != nil {
+			return false, fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		keyPEM, err := os.ReadFile(pair.keyFile)
+		if err != nil {
+			return false, fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		contents = append(contents, []byte(pair.certFile), certPEM, keyPEM)
+	}
+
+	var caPEM []byte
+	if m.caFile != "" {
+		if caPEM, err = os.ReadFile(m.caFile); err != nil {
+			return false, fmt.Errorf("os.ReadFile: %w", err)
+		}
+	}
+
+	checksum := sha256.Sum256(bytes.Join(append(contents, caPEM), []byte{0}))
+	if checksum == m.checksum {
+		return false, nil
+	}
+
+	var certs []*namedCertificate
+	if m.static != nil {
+		certs = append(certs, m.static)
+	}
+
+	for i, pair := range pairs {
+		cert, err := tls.X509KeyPair(contents[i*3+1], contents[i*3+2])
+		if err != nil {
+			return false, fmt.Errorf("tls.X509KeyPair %s: %w", pair.certFile, err)
+		}
+
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return false, fmt.Errorf("x509.ParseCertificate %s: %w", pair.certFile, err)
+		}
+
+		certs = append(certs, &namedCertificate{name: pair.certFile, cert: &cert})
+	}
+
+	var pool *x509.CertPool
+	if m.caFile != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return false, fmt.Errorf("no valid PEM certificates found in %s", m.caFile)
+		}
+	}
+
+	m.certs.Store(&certs)
+
+	// Drop the series of the certificates that are no longer loaded, as the removed directory files.
+	promCertExpiry.Reset()
+
+	for _, nc := range certs {
+		promCertExpiry.WithLabelValues("serving", nc.name).Set(float64(nc.cert.Leaf.NotAfter.Unix()))
+	}
+
+	if pool != nil {
+		m.caPool.Store(pool)
+		promCertExpiry.WithLabelValues("ca", m.caFile).Set(float64(earliestExpiry(caPEM).Unix()))
+	}
+
+	m.checksum = checksum
+
+	return true, nil
+}
+
+// listPairs returns the configured certificate and key pairs followed by
+// the <name>.crt and <name>.key pairs found in the certificate directory.
+func (m *certManager) listPairs() ([]certKeyPair, error) {
+	pairs := append([]certKeyPair{}, m.pairs...)
+
+	if m.certDir == "" {
+		return pairs, nil
+	}
+
+	certFiles, err := filepath.Glob(filepath.Join(m.certDir, "*.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("filepath.Glob: %w", err)
+	}
+
+	sort.Strings(certFiles)
+
+	for _, certFile := range certFiles {
+		keyFile := strings.TrimSuffix(certFile, ".crt") + ".key"
+		if _, err := os.Stat(keyFile); err != nil {
+			continue
+		}
+
+		pairs = append(pairs, certKeyPair{certFile: certFile, keyFile: keyFile})
+	}
+
+	return pairs, nil
+}
+
+// earliestExpiry returns the earliest expiry time of the PEM encoded certificates.
+func earliestExpiry(data []byte) time.Time {
+	var expiry time.Time
+
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return expiry
+		}
+
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		if expiry.IsZero() || crt.NotAfter.Before(expiry) {
+			expiry = crt.NotAfter
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Client address sources.
+const (
+	clientSourceRemoteAddr    = "remote_addr"
+	clientSourceForwarded     = "forwarded"
+	clientSourceXForwardedFor = "x-forwarded-for"
+	clientSourceXRealIP       = "x-real-ip"
+)
+
+type clientInfo struct {
+	IP     string      `json:"ip"`
+	Source string      `json:"source"`
+	Proto  string      `json:"proto"`
+	Host   string      `json:"host"`
+	Port   string      `json:"port,omitempty"`
+	Hops   []clientHop `json:"hops"`
+}
+
+// clientHop is a hop of the proxy chain, from the original client to the direct peer.
+type clientHop struct {
+	Addr    string `json:"addr"`
+	Source  string `json:"source"`
+	Trusted bool   `json:"trusted"`
+	Client  bool   `json:"client,omitempty"`
+}
+
+// forwardedElement is an element of the RFC 7239 Forwarded header.
+type forwardedElement struct {
+	forwardedFor string
+	proto        string
+	host         string
+}
+
+// getClientInfo resolves the client address of the request through the trusted proxies.
+//
+// The hops are read from the Forwarded header, or from the X-Forwarded-For header if it is absent,
+// followed by the direct peer. The chain is walked from the direct peer to the original client
+// while the hops are trusted: the first untrusted hop is the client. The X-Real-IP header is used
+// if there is no forwarding chain. The X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Port
+// headers, or the Forwarded proto and host parameters, are used only if the direct peer is trusted.
+//
+// Parameters:
+// - r: The request.
+// - trustedProxies: The list of the trusted proxies networks.
+//
+// Returns:
+// - *clientInfo: The client details.
+func getClientInfo(r *http.Request, trustedProxies []netip.Prefix) *clientInfo {
+	var hops []clientHop
+
+	forwarded := parseForwarded(r.Header.Values("Forwarded"))
+
+	if len(forwarded) > 0 {
+		for _, elem := range forwarded {
+			hops = append(hops, clientHop{Addr: elem.forwardedFor, Source: clientSourceForwarded})
+		}
+	} else {
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			for _, addr := range strings.Split(value, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					hops = append(hops, clientHop{Addr: addr, Source: clientSourceXForwardedFor})
+				}
+			}
+		}
+	}
+
+	hops = append(hops, clientHop{Addr: r.RemoteAddr, Source: clientSourceRemoteAddr})
+
+	for i := range hops {
+		hops[i].Trusted = isTrustedProxy(hops[i].Addr, trustedProxies)
+	}
+
+	// The client is the first untrusted hop from the direct peer, or the original client if all are trusted.
+	client := 0
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !hops[i].Trusted {
+			client = i
+
+			break
+		}
+	}
+
+	peerTrusted := hops[len(hops)-1].Trusted
+
+	info := &clientInfo{
+		IP:     hostOnly(hops[client].Addr),
+		Source: hops[client].Source,
+		Proto:  "http",
+		Host:   r.Host,
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" && peerTrusted && len(hops) == 1 {
+		hops = append([]clientHop{{Addr: realIP, Source: clientSourceXRealIP}}, hops...)
+		client = 0
+		info.IP = hostOnly(realIP)
+		info.Source = clientSourceXRealIP
+	}
+
+	hops[client].Client = true
+	info.Hops = hops
+
+	if r.TLS != nil {
+		info.Proto = "https"
+	}
+
+	if peerTrusted {
+		info.Proto = valueOr(firstHeaderValue(r.Header, "X-Forwarded-Proto"), info.Proto)
+		info.Host = valueOr(firstHeaderValue(r.Header, "X-Forwarded-Host"), info.Host)
+		info.Port = firstHeaderValue(r.Header, "X-Forwarded-Port")
+
+		// The Forwarded parameters of the element added by the proxy in front of the client take precedence.
+		if len(forwarded) > 0 {
+			elem := forwarded[min(client, len(forwarded)-1)]
+			info.Proto = valueOr(elem.proto, info.Proto)
+			info.Host = valueOr(elem.host, info.Host)
+		}
+	}
+
+	if info.Port == "" {
+		if _, port, err := net.SplitHostPort(info.Host); err == nil {
+			info.Port = port
+		}
+	}
+
+	return info
+}
+
+// isTrustedProxy reports whether the address belongs to any of the trusted networks.
+func isTrustedProxy(addr string, trustedProxies []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(hostOnly(addr))
+	if err != nil {
+		return false
+	}
+
+	ip = ip.Unmap()
+
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostOnly strips the port and the IPv6 brackets of the address.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// parseForwarded parses the elements of the RFC 7239 Forwarded header values.
+// The elements without the "for" parameter are skipped.
+func parseForwarded(values []string) []forwardedElement {
+	var elements []forwardedElement
+
+	for _, value := range values {
+		for _, elem := range splitQuoted(value, ',') {
+			var fe forwardedElement
+
+			for _, pair := range splitQuoted(elem, ';') {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "for":
+					fe.forwardedFor = val
+				case "proto":
+					fe.proto = strings.ToLower(val)
+				case "host":
+					fe.host = val
+				}
+			}
+
+			if fe.forwardedFor != "" {
+				elements = append(elements, fe)
+			}
+		}
+	}
+
+	return elements
+}
+
+// splitQuoted splits the string by the separator outside of the quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		start  int
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// firstHeaderValue returns the first comma separated value of the header.
+func firstHeaderValue(h http.Header, key string) string {
+	value, _, _ := strings.Cut(h.Get(key), ",")
+
+	return strings.TrimSpace(value)
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
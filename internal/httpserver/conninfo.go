@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/quic-go/quic-go"
+)
+
+// connInfoKey is the connection context key of the *connInfo.
+type connInfoKey struct{}
+
+// connectionRequestKey is the request context key of the request sequence number on its connection.
+type connectionRequestKey struct{}
+
+// responseControllerKey is the request context key of the *http.ResponseController of the server response writer.
+type responseControllerKey struct{}
+
+// connInfo holds the state of a client connection shared by all its requests.
+type connInfo struct {
+	// servedCertificate is the certificate selected during the TLS handshake.
+	servedCertificate atomic.Pointer[servedCertificateInfo]
+
+	// requests is the number of requests served on the connection.
+	requests atomic.Int64
+
+	// quicConn is the QUIC connection of HTTP/3 requests.
+	quicConn quic.Connection
+
+	// proxyConn is the connection of the listeners accepting the PROXY protocol.
+	proxyConn *proxyproto.Conn
+}
+
+// withConnInfo returns the connection context holding a new connInfo.
+// It is used as http.Server.ConnContext.
+func withConnInfo(ctx context.Context, c net.Conn) context.Context {
+	info := &connInfo{}
+	if c != nil {
+		info.proxyConn = unwrapProxyProtocolConn(c)
+	}
+
+	return context.WithValue(ctx, connInfoKey{}, info)
+}
+
+// getConnInfo returns the connInfo of the connection context or nil.
+func getConnInfo(ctx context.Context) *connInfo {
+	conn, ok := ctx.Value(connInfoKey{}).(*connInfo)
+	if !ok {
+		return nil
+	}
+
+	return conn
+}
+
+// getServedCertificate returns the certificate selected for the request connection or nil.
+func getServedCertificate(ctx context.Context) *servedCertificateInfo {
+	conn := getConnInfo(ctx)
+	if conn == nil {
+		return nil
+	}
+
+	return conn.servedCertificate.Load()
+}
+
+// getProxyProtocolConn returns the PROXY protocol connection of the request or nil.
+func getProxyProtocolConn(ctx context.Context) *proxyproto.Conn {
+	conn := getConnInfo(ctx)
+	if conn == nil {
+		return nil
+	}
+
+	return conn.proxyConn
+}
+
+// countRequests wraps the handler to number the requests served on each connection.
+func countRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn := getConnInfo(r.Context()); conn != nil {
+			r = r.WithContext(context.WithValue(r.Context(), connectionRequestKey{}, conn.requests.Add(1)))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getConnectionRequest returns the sequence number of the request on its connection or zero.
+func getConnectionRequest(ctx context.Context) int64 {
+	seq, _ := ctx.Value(connectionRequestKey{}).(int64)
+
+	return seq
+}
+
+// withResponseController wraps the handler to pass the controller of the server response writer in the
+// request context. The access log and metrics response writers do not implement Unwrap, so the controllers
+// created by the handlers cannot reach the server response writer to change its deadlines.
+func withResponseController(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), responseControllerKey{}, rc)))
+	})
+}
+
+// getResponseController returns the controller of the server response writer of the request,
+// or the controller of the given response writer if it is not set.
+func getResponseController(r *http.Request, w http.ResponseWriter) *http.ResponseController {
+	if rc, ok := r.Context().Value(responseControllerKey{}).(*http.ResponseController); ok {
+		return rc
+	}
+
+	return http.NewResponseController(w)
+}
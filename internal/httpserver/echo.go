@@ -0,0 +1,251 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Echo server networks.
+const (
+	echoTCP = "tcp"
+	echoUDP = "udp"
+)
+
+// echoMaxDatagramSize is the size of the UDP read buffer, larger datagrams are truncated.
+const echoMaxDatagramSize = 64 * 1024
+
+var (
+	promEchoConnections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "whoami",
+			Subsystem: "echo",
+			Name:      "connections_total",
+			Help:      "Total number of TCP echo connections and UDP echo datagrams.",
+		}, []string{"protocol"})
+
+	promEchoActiveConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "whoami",
+			Subsystem: "echo",
+			Name:      "active_connections",
+			Help:      "Number of open TCP echo connections.",
+		}, []string{"protocol"})
+)
+
+// echoServer replies to the TCP connections and UDP datagrams with a banner
+// holding the hostname and the connection addresses, then echoes the received bytes back.
+type echoServer struct {
+	network       string
+	addr          string
+	proxyProtocol *proxyProtocolOptions
+	hostInfo      *hostInfoProvider
+
+	mu       sync.Mutex
+	listener net.Listener
+	packet   net.PacketConn
+	conns    map[net.Conn]struct{}
+	closed   bool
+
+	wg sync.WaitGroup
+}
+
+// newEchoServer creates an echo server on the given network and address.
+// The PROXY protocol is accepted by the TCP server if proxyProtocol is set.
+func newEchoServer(network, addr string, proxyProtocol *proxyProtocolOptions, hostInfo *hostInfoProvider) *echoServer {
+	return &echoServer{
+		network:       network,
+		addr:          addr,
+		proxyProtocol: proxyProtocol,
+		hostInfo:      hostInfo,
+		conns:         make(map[net.Conn]struct{}),
+	}
+}
+
+// serve starts the echo server and blocks until it is stopped.
+func (s *echoServer) serve() error {
+	if s.network == echoUDP {
+		return s.serveUDP()
+	}
+
+	return s.serveTCP()
+}
+
+func (s *echoServer) serveTCP() error {
+	ln, err := listenTCP(s.addr, s.proxyProtocol)
+	if err != nil {
+		return fmt.Errorf("listenTCP: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+
+		return ln.Close()
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("listener.Accept: %w", err)
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+
+			return nil
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+// track adds the connection to the open ones. It returns false if the server is closed.
+func (s *echoServer) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+func (s *echoServer) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+
+	promEchoConnections.WithLabelValues(echoTCP).Inc()
+	promEchoActiveConnections.WithLabelValues(echoTCP).Inc()
+
+	defer func() {
+		promEchoActiveConnections.WithLabelValues(echoTCP).Dec()
+
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+
+		conn.Close()
+	}()
+
+	banner := echoBanner(s.hostInfo.get().hostname, conn.LocalAddr(), conn.RemoteAddr())
+
+	if proxy := getProxyProtocolInfo(unwrapProxyProtocolConn(conn)); proxy != nil {
+		banner += fmt.Sprintf("ProxyProtocol: v%d %s PeerAddr=%s\n", proxy.Version, proxy.Command, proxy.PeerAddr)
+	}
+
+	if _, err := io.WriteString(conn, banner); err != nil {
+		return
+	}
+
+	if _, err := io.Copy(conn, conn); err != nil && !errors.Is(err, net.ErrClosed) {
+		slog.Debug(fmt.Sprintf("TCP echo connection from %s failed: %v", conn.RemoteAddr(), err))
+	}
+}
+
+func (s *echoServer) serveUDP() error {
+	pc, err := net.ListenPacket("udp", s.addr)
+	if err != nil {
+		return fmt.Errorf("net.ListenPacket: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+
+		return pc.Close()
+	}
+	s.packet = pc
+	s.mu.Unlock()
+
+	buf := make([]byte, echoMaxDatagramSize)
+
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("packetConn.ReadFrom: %w", err)
+		}
+
+		promEchoConnections.WithLabelValues(echoUDP).Inc()
+
+		// The banner and the echoed payload are sent as separate datagrams.
+		if _, err := pc.WriteTo([]byte(echoBanner(s.hostInfo.get().hostname, pc.LocalAddr(), addr)), addr); err != nil {
+			slog.Debug(fmt.Sprintf("UDP echo reply to %s failed: %v", addr, err))
+
+			continue
+		}
+
+		if _, err := pc.WriteTo(buf[:n], addr); err != nil {
+			slog.Debug(fmt.Sprintf("UDP echo reply to %s failed: %v", addr, err))
+		}
+	}
+}
+
+// shutdown stops accepting connections and closes the open ones.
+// It waits for the connection handlers to return until the context is done.
+func (s *echoServer) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+
+	var errs []error
+
+	if s.listener != nil {
+		errs = append(errs, s.listener.Close())
+	}
+
+	if s.packet != nil {
+		errs = append(errs, s.packet.Close())
+	}
+
+	for conn := range s.conns {
+		errs = append(errs, conn.Close())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+
+	return errors.Join(errs...)
+}
+
+// echoBanner returns the banner sent to the echo clients.
+func echoBanner(hostname string, localAddr, remoteAddr net.Addr) string {
+	return fmt.Sprintf(
+		"Hostname: %s\n"+
+			"LocalAddr: %s\n"+
+			"RemoteAddr: %s\n",
+		hostname,
+		localAddr,
+		remoteAddr,
+	)
+}
@@ -0,0 +1,431 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// faultHeaderPrefix is the prefix of the request headers setting the fault parameters,
+// ex. X-Whoami-Fault-Error-Rate for the error_rate parameter.
+const faultHeaderPrefix = "X-Whoami-Fault-"
+
+// faultQueryPrefix is the prefix of the query parameters setting the fault parameters,
+// ex. fault_error_rate for the error_rate parameter, so the other query parameters are echoed as is.
+const faultQueryPrefix = "fault_"
+
+// Fault parameters.
+const (
+	faultStatus       = "status"
+	faultErrorRate    = "error_rate"
+	faultErrorCode    = "error_code"
+	faultDelay        = "delay"
+	faultDelayDist    = "delay_dist"
+	faultDelayMax     = "delay_max"
+	faultDelayStddev  = "delay_stddev"
+	faultAbort        = "abort"
+	faultTruncate     = "truncate"
+	faultDribble      = "dribble"
+	faultDribbleBytes = "dribble_bytes"
+)
+
+var faultParams = []string{
+	faultStatus, faultErrorRate, faultErrorCode,
+	faultDelay, faultDelayDist, faultDelayMax, faultDelayStddev,
+	faultAbort, faultTruncate, faultDribble, faultDribbleBytes,
+}
+
+// Latency distributions.
+const (
+	delayFixed       = "fixed"
+	delayUniform     = "uniform"
+	delayNormal      = "normal"
+	delayExponential = "exponential"
+)
+
+// Connection abort modes.
+const (
+	abortReset = "reset"
+	abortHang  = "hang"
+	abortClose = "close"
+)
+
+var promFaultsInjected = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "whoami",
+		Subsystem: "fault",
+		Name:      "injected_total",
+		Help:      "Total number of injected faults.",
+	}, []string{"type"})
+
+// faultSpec describes the faults injected into a request.
+type faultSpec struct {
+	status       int     // Forced response status, 0 if unset.
+	errorRate    float64 // Probability of answering with errorCode.
+	errorCode    int
+	delay        time.Duration // Fixed delay, lower bound of the uniform distribution or mean of the others.
+	delayDist    string
+	delayMax     time.Duration // Upper bound of the uniform distribution.
+	delayStddev  time.Duration // Standard deviation of the normal distribution.
+	abort        string
+	truncate     int64         // Number of body bytes sent before the connection is aborted, -1 if unset.
+	dribble      time.Duration // Pause between the body chunks.
+	dribbleBytes int           // Size of the body chunks.
+}
+
+// parseFaultSpec parses the fault parameters returned by the get function.
+// It returns nil if none of the parameters is set.
+func parseFaultSpec(get func(key string) string) (*faultSpec, error) {
+	set := false
+	for _, key := range faultParams {
+		if get(key) != "" {
+			set = true
+
+			break
+		}
+	}
+
+	if !set {
+		return nil, nil
+	}
+
+	spec := &faultSpec{
+		errorCode:    http.StatusInternalServerError,
+		delayDist:    delayFixed,
+		truncate:     -1,
+		dribbleBytes: 1,
+	}
+
+	var errs []error
+
+	parseInt := func(key string, v *int, minValue, maxValue int) {
+		if s := get(key); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < minValue || n > maxValue {
+				errs = append(errs, fmt.Errorf("invalid %s: %q", key, s))
+
+				return
+			}
+
+			*v = n
+		}
+	}
+
+	parseDuration := func(key string, v *time.Duration) {
+		if s := get(key); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				errs = append(errs, fmt.Errorf("invalid %s: %q", key, s))
+
+				return
+			}
+
+			*v = d
+		}
+	}
+
+	parseInt(faultStatus, &spec.status, 100, 599)
+	parseInt(faultErrorCode, &spec.errorCode, 100, 599)
+	parseInt(faultDribbleBytes, &spec.dribbleBytes, 1, 1<<20)
+	parseDuration(faultDelay, &spec.delay)
+	parseDuration(faultDelayMax, &spec.delayMax)
+	parseDuration(faultDelayStddev, &spec.delayStddev)
+	parseDuration(faultDribble, &spec.dribble)
+
+	if s := get(faultErrorRate); s != "" {
+		rate, err := strconv.ParseFloat(s, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			errs = append(errs, fmt.Errorf("invalid %s: %q", faultErrorRate, s))
+		}
+
+		spec.errorRate = rate
+	}
+
+	if s := get(faultTruncate); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s: %q", faultTruncate, s))
+		}
+
+		spec.truncate = n
+	}
+
+	if s := get(faultDelayDist); s != "" {
+		switch s = strings.ToLower(s); s {
+		case delayFixed, delayUniform, delayNormal, delayExponential:
+			spec.delayDist = s
+		default:
+			errs = append(errs, fmt.Errorf("invalid %s: %q", faultDelayDist, s))
+		}
+	}
+
+	if spec.delayDist == delayUniform && spec.delayMax < spec.delay {
+		errs = append(errs, fmt.Errorf("%s must not be less than %s", faultDelayMax, faultDelay))
+	}
+
+	if s := get(faultAbort); s != "" {
+		switch s = strings.ToLower(s); s {
+		case abortReset, abortHang, abortClose:
+			spec.abort = s
+		default:
+			errs = append(errs, fmt.Errorf("invalid %s: %q", faultAbort, s))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return spec, nil
+}
+
+// sampleDelay returns a delay drawn from the latency distribution. Negative samples are clamped to zero.
+func (f *faultSpec) sampleDelay() time.Duration {
+	var d float64
+
+	switch f.delayDist {
+	case delayUniform:
+		d = float64(f.delay) + rand.Float64()*float64(f.delayMax-f.delay)
+	case delayNormal:
+		d = float64(f.delay) + rand.NormFloat64()*float64(f.delayStddev)
+	case delayExponential:
+		d = rand.ExpFloat64() * float64(f.delay)
+	default:
+		d = float64(f.delay)
+	}
+
+	return time.Duration(max(d, 0))
+}
+
+// faultHandler wraps the handler to inject the faults of the first matching fault rule, or else the faults
+// set by the fault_* query parameters, the X-Whoami-Fault-* request headers or the server-side defaults,
+// in this order of precedence. The delay query parameter is kept as an alias of fault_delay.
+//
+// The delay is applied first, then the connection abort, the forced status and the probabilistic error,
+// which answer the request without calling the handler. The body truncation and dribbling apply to
+// the response of the handler.
+//
+// Parameters:
+// - next: The handler to wrap.
+// - defaults: The server-side fault parameters.
+// - rules: The fault rules managed through the admin API.
+//
+// Returns:
+// - http.Handler: The fault injection handler.
+func faultHandler(next http.Handler, defaults url.Values, rules *faultRules) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if spec := rules.match(r); spec != nil {
+			spec.serve(w, r, next)
+
+			return
+		}
+
+		query := r.URL.Query()
+
+		spec, err := parseFaultSpec(func(key string) string {
+			if v := query.Get(faultQueryPrefix + key); v != "" {
+				return v
+			}
+
+			if v := query.Get(key); v != "" && key == faultDelay {
+				return v
+			}
+
+			if v := r.Header.Get(faultHeaderPrefix + strings.ReplaceAll(key, "_", "-")); v != "" {
+				return v
+			}
+
+			return defaults.Get(key)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if spec == nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		spec.serve(w, r, next)
+	})
+}
+
+// serve injects the faults into the request served by the handler.
+func (f *faultSpec) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if d := f.sampleDelay(); d > 0 {
+		promFaultsInjected.WithLabelValues("delay").Inc()
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		}
+	}
+
+	if f.abort != "" {
+		promFaultsInjected.WithLabelValues(f.abort).Inc()
+		abortConnection(w, r, f.abort)
+
+		return
+	}
+
+	if f.status != 0 {
+		promFaultsInjected.WithLabelValues("status").Inc()
+		http.Error(w, http.StatusText(f.status), f.status)
+
+		return
+	}
+
+	if f.errorRate > 0 && rand.Float64() < f.errorRate {
+		promFaultsInjected.WithLabelValues("error").Inc()
+		http.Error(w, http.StatusText(f.errorCode), f.errorCode)
+
+		return
+	}
+
+	if f.truncate < 0 && f.dribble <= 0 {
+		next.ServeHTTP(w, r)
+
+		return
+	}
+
+	fw := &faultWriter{
+		ResponseWriter: w,
+		rc:             http.NewResponseController(w),
+		done:           r.Context().Done(),
+		limit:          f.truncate,
+		dribble:        f.dribble,
+		dribbleBytes:   f.dribbleBytes,
+	}
+
+	if f.dribble > 0 {
+		promFaultsInjected.WithLabelValues("dribble").Inc()
+	}
+
+	next.ServeHTTP(fw, r)
+
+	if fw.truncated {
+		promFaultsInjected.WithLabelValues("truncate").Inc()
+
+		// Send the written bytes and abort the response so the client sees an incomplete body.
+		_ = fw.rc.Flush()
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// abortConnection aborts the connection of the request without sending a response.
+//
+// The reset mode closes the TCP connection with a RST, the close mode closes it gracefully and
+// the hang mode keeps it open until the client gives up. The connections that cannot be hijacked,
+// as HTTP/2 and HTTP/3 streams, are reset with the stream error.
+func abortConnection(w http.ResponseWriter, r *http.Request, mode string) {
+	if mode == abortHang {
+		<-r.Context().Done()
+
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	if mode == abortReset {
+		if tcpConn := unwrapTCPConn(conn); tcpConn != nil {
+			_ = tcpConn.SetLinger(0)
+		}
+	}
+
+	_ = conn.Close()
+}
+
+// unwrapTCPConn returns the TCP connection underlying the connection or nil.
+func unwrapTCPConn(c net.Conn) *net.TCPConn {
+	for {
+		switch conn := c.(type) {
+		case *net.TCPConn:
+			return conn
+		case interface{ NetConn() net.Conn }: // *tls.Conn
+			c = conn.NetConn()
+		case interface{ Raw() net.Conn }: // *proxyproto.Conn
+			c = conn.Raw()
+		default:
+			return nil
+		}
+	}
+}
+
+// faultWriter truncates and dribbles the response body.
+type faultWriter struct {
+	http.ResponseWriter
+	rc   *http.ResponseController
+	done <-chan struct{}
+
+	limit     int64 // -1 if the body is not truncated.
+	written   int64
+	truncated bool
+
+	dribble      time.Duration
+	dribbleBytes int
+}
+
+func (w *faultWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if w.limit >= 0 && w.written+int64(len(p)) > w.limit {
+		p = p[:w.limit-w.written]
+		w.truncated = true
+	}
+
+	chunkSize := len(p)
+	if w.dribble > 0 {
+		chunkSize = w.dribbleBytes
+	}
+
+	// The truncated bytes are reported as written so the handler completes the response.
+	for sent := 0; sent < len(p); {
+		if sent > 0 {
+			select {
+			case <-w.done:
+				return sent, errors.New("request canceled")
+			case <-time.After(w.dribble):
+			}
+		}
+
+		written, err := w.ResponseWriter.Write(p[sent:min(sent+chunkSize, len(p))])
+		w.written += int64(written)
+		sent += written
+
+		if err != nil {
+			return sent, err
+		}
+
+		if w.dribble > 0 {
+			if err := w.rc.Flush(); err != nil {
+				return sent, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Unwrap returns the wrapped http.ResponseWriter for http.ResponseController.
+func (w *faultWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,69 @@
+package httpserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/andymarkow/whoami/internal/httpserver"
+)
+
+func TestFaultQueryParams(t *testing.T) {
+	addr, _ := startServer(t, &httpserver.Config{})
+
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	// The query parameters named after the fault parameters are echoed instead of being interpreted.
+	resp, err := client.Get("http://" + addr + "/api?status=active&abort=false&truncate=yes")
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var data struct {
+		Params map[string][]string `json:"params"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		t.Fatalf("json.Decode: %v", err)
+	}
+
+	if got := data.Params["status"]; len(got) != 1 || got[0] != "active" {
+		t.Errorf("params.status = %q, want [active]", got)
+	}
+
+	// The prefixed query parameters inject the faults.
+	resp, err = client.Get("http://" + addr + "/api?fault_status=503")
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestFaultRulesDisabled(t *testing.T) {
+	addr, _ := startServer(t, &httpserver.Config{})
+
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	// The fault rules API is not served without the admin listener, nor the whoami route in its place.
+	for _, path := range []string{"/faults", "/faults/", "/faults/1"} {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatalf("client.Get: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", path, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+}
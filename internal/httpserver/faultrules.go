@@ -0,0 +1,420 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Fault rule actions.
+const (
+	faultActionDelay    = "delay"
+	faultActionAbort    = "abort"
+	faultActionReset    = "reset"
+	faultActionThrottle = "throttle"
+)
+
+// faultThrottleInterval is the pause between the response body chunks of the throttle action.
+const faultThrottleInterval = 100 * time.Millisecond
+
+var promFaultRuleHits = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "whoami",
+		Subsystem: "fault",
+		Name:      "rule_hits_total",
+		Help:      "Total number of requests faulted by the fault rules.",
+	}, []string{"rule", "action"})
+
+// faultRule describes a fault rule as reported by the admin API.
+type faultRule struct {
+	ID         string            `json:"id"`
+	PathPrefix string            `json:"path_prefix,omitempty"`
+	Method     string            `json:"method,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	SourceCIDR string            `json:"source_cidr,omitempty"`
+	Action     faultAction       `json:"action"`
+	Percentage float64           `json:"percentage"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Hits       uint64            `json:"hits"`
+}
+
+// faultAction is the fault injected by a rule. Delay is set for the delay action, Status for
+// the abort action and BytesPerSecond for the throttle action.
+type faultAction struct {
+	Type           string `json:"type"`
+	Delay          string `json:"delay,omitempty"`
+	Status         int    `json:"status,omitempty"`
+	BytesPerSecond int64  `json:"bytes_per_second,omitempty"`
+}
+
+// faultRuleRequest is the body of the fault rule creation request.
+type faultRuleRequest struct {
+	PathPrefix string            `json:"path_prefix"`
+	Method     string            `json:"method"`
+	Headers    map[string]string `json:"headers"`
+	SourceCIDR string            `json:"source_cidr"`
+	Action     faultAction       `json:"action"`
+	Percentage *float64          `json:"percentage"`
+	ExpiresAt  *time.Time        `json:"expires_at"`
+	TTL        string            `json:"ttl"`
+}
+
+// activeFaultRule is a fault rule with its matching state.
+type activeFaultRule struct {
+	faultRule
+
+	source      netip.Prefix // Invalid if the rule matches any source.
+	spec        *faultSpec
+	hits        atomic.Uint64
+	hitsCounter prometheus.Counter
+}
+
+// faultRules is the concurrency-safe list of the fault rules, matched in creation order.
+type faultRules struct {
+	trustedProxies []netip.Prefix
+
+	mu    sync.RWMutex
+	rules []*activeFaultRule
+}
+
+// newFaultRules creates an empty list of fault rules.
+//
+// Parameters:
+// - trustedProxies: The networks of the proxies trusted to set the forwarding headers. The source CIDR
+// of the rules is matched against the client address resolved through them.
+//
+// Returns:
+// - *faultRules: The fault rules.
+func newFaultRules(trustedProxies []netip.Prefix) *faultRules {
+	return &faultRules{trustedProxies: trustedProxies}
+}
+
+// newFaultRule validates the creation request and returns the rule.
+func newFaultRule(req *faultRuleRequest, now time.Time) (*activeFaultRule, error) {
+	rule := &activeFaultRule{
+		faultRule: faultRule{
+			ID:         uuid.New().String(),
+			PathPrefix: req.PathPrefix,
+			Method:     strings.ToUpper(req.Method),
+			Headers:    req.Headers,
+			SourceCIDR: req.SourceCIDR,
+			Action:     req.Action,
+			Percentage: 100,
+			ExpiresAt:  req.ExpiresAt,
+			CreatedAt:  now.UTC(),
+		},
+	}
+
+	if req.Percentage != nil {
+		if *req.Percentage < 0 || *req.Percentage > 100 {
+			return nil, fmt.Errorf("invalid percentage: %g", *req.Percentage)
+		}
+
+		rule.Percentage = *req.Percentage
+	}
+
+	if req.TTL != "" && req.ExpiresAt != nil {
+		return nil, errors.New("ttl and expires_at are mutually exclusive")
+	}
+
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl: %q", req.TTL)
+		}
+
+		expiresAt := rule.CreatedAt.Add(ttl)
+		rule.ExpiresAt = &expiresAt
+	}
+
+	if rule.expired(now) {
+		return nil, fmt.Errorf("expires_at is in the past: %s", rule.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if req.SourceCIDR != "" {
+		prefix, err := netip.ParsePrefix(req.SourceCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source_cidr: %w", err)
+		}
+
+		rule.source = prefix.Masked()
+	}
+
+	spec := &faultSpec{delayDist: delayFixed, truncate: -1}
+
+	switch req.Action.Type {
+	case faultActionDelay:
+		d, err := time.ParseDuration(req.Action.Delay)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid action delay: %q", req.Action.Delay)
+		}
+
+		spec.delay = d
+	case faultActionAbort:
+		if req.Action.Status < 100 || req.Action.Status > 599 {
+			return nil, fmt.Errorf("invalid action status: %d", req.Action.Status)
+		}
+
+		spec.status = req.Action.Status
+	case faultActionReset:
+		spec.abort = abortReset
+	case faultActionThrottle:
+		bps := req.Action.BytesPerSecond
+		if bps <= 0 {
+			return nil, fmt.Errorf("invalid action bytes_per_second: %d", bps)
+		}
+
+		// Send a chunk every interval, or a byte at the rate if it is lower than a byte per interval.
+		spec.dribble = faultThrottleInterval
+		spec.dribbleBytes = int(bps * int64(faultThrottleInterval) / int64(time.Second))
+
+		if spec.dribbleBytes < 1 {
+			spec.dribble = time.Second / time.Duration(bps)
+			spec.dribbleBytes = 1
+		}
+	default:
+		return nil, fmt.Errorf("invalid action type: %q", req.Action.Type)
+	}
+
+	rule.spec = spec
+	rule.hitsCounter = promFaultRuleHits.WithLabelValues(rule.ID, req.Action.Type)
+
+	return rule, nil
+}
+
+// expired reports whether the rule has expired at the given time.
+func (rule *activeFaultRule) expired(now time.Time) bool {
+	return rule.ExpiresAt != nil && !now.Before(*rule.ExpiresAt)
+}
+
+// matches reports whether the request matches the rule conditions.
+// The client address is resolved lazily as it is needed by the rules with a source CIDR only.
+func (rule *activeFaultRule) matches(r *http.Request, clientIP func() netip.Addr) bool {
+	if rule.PathPrefix != "" && !strings.HasPrefix(r.URL.Path, rule.PathPrefix) {
+		return false
+	}
+
+	if rule.Method != "" && rule.Method != r.Method {
+		return false
+	}
+
+	for name, value := range rule.Headers {
+		if !slices.Contains(r.Header.Values(name), value) {
+			return false
+		}
+	}
+
+	if rule.source.IsValid() && !rule.source.Contains(clientIP()) {
+		return false
+	}
+
+	return rule.Percentage >= 100 || rand.Float64()*100 < rule.Percentage
+}
+
+// snapshot returns a copy of the rule for the JSON encoding.
+func (rule *activeFaultRule) snapshot() faultRule {
+	snapshot := rule.faultRule
+	snapshot.Hits = rule.hits.Load()
+
+	return snapshot
+}
+
+// match returns the fault spec of the first rule matching the request and counts the hit, or nil.
+func (rs *faultRules) match(r *http.Request) *faultSpec {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	if len(rs.rules) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	var clientIP netip.Addr
+
+	resolve := func() netip.Addr {
+		if !clientIP.IsValid() {
+			clientIP, _ = netip.ParseAddr(getClientInfo(r, rs.trustedProxies).IP)
+			clientIP = clientIP.Unmap()
+		}
+
+		return clientIP
+	}
+
+	for _, rule := range rs.rules {
+		if rule.expired(now) || !rule.matches(r, resolve) {
+			continue
+		}
+
+		rule.hits.Add(1)
+		rule.hitsCounter.Inc()
+
+		return rule.spec
+	}
+
+	return nil
+}
+
+// list returns the active rules and removes the expired ones.
+func (rs *faultRules) list() []faultRule {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.purge(time.Now())
+
+	result := make([]faultRule, 0, len(rs.rules))
+	for _, rule := range rs.rules {
+		result = append(result, rule.snapshot())
+	}
+
+	return result
+}
+
+// add adds the rule to the list and removes the expired ones.
+func (rs *faultRules) add(rule *activeFaultRule) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.purge(time.Now())
+	rs.rules = append(rs.rules, rule)
+}
+
+// get returns the rule with the given ID.
+func (rs *faultRules) get(id string) (faultRule, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	now := time.Now()
+
+	for _, rule := range rs.rules {
+		if rule.ID == id && !rule.expired(now) {
+			return rule.snapshot(), true
+		}
+	}
+
+	return faultRule{}, false
+}
+
+// delete removes the rule with the given ID or all the rules if the ID is empty.
+// It returns false if there is no rule with the ID.
+func (rs *faultRules) delete(id string) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	found := false
+
+	rs.rules = slices.DeleteFunc(rs.rules, func(rule *activeFaultRule) bool {
+		if id != "" && rule.ID != id {
+			return false
+		}
+
+		found = true
+		promFaultRuleHits.DeleteLabelValues(rule.ID, rule.Action.Type)
+
+		return true
+	})
+
+	return found || id == ""
+}
+
+// purge removes the expired rules and their metrics. It must be called with the lock held.
+func (rs *faultRules) purge(now time.Time) {
+	rs.rules = slices.DeleteFunc(rs.rules, func(rule *activeFaultRule) bool {
+		if !rule.expired(now) {
+			return false
+		}
+
+		promFaultRuleHits.DeleteLabelValues(rule.ID, rule.Action.Type)
+
+		return true
+	})
+}
+
+// faultRulesHandler manages the fault rules.
+//
+// The /faults path lists the rules on GET, creates a rule from the JSON body on POST and
+// deletes all the rules on DELETE. The /faults/<id> path returns the rule on GET and deletes it on DELETE.
+//
+// Parameters:
+// - rules: The fault rules.
+//
+// Returns:
+// - http.Handler: The fault rules admin handler.
+func faultRulesHandler(rules *faultRules) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/faults"), "/")
+
+		switch {
+		case id == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, rules.list())
+		case id == "" && r.Method == http.MethodPost:
+			var req faultRuleRequest
+
+			dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*KB))
+			dec.DisallowUnknownFields()
+
+			if err := dec.Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("invalid fault rule: %v", err), http.StatusBadRequest)
+
+				return
+			}
+
+			rule, err := newFaultRule(&req, time.Now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			rules.add(rule)
+			writeJSON(w, http.StatusCreated, rule.snapshot())
+		case r.Method == http.MethodGet:
+			rule, ok := rules.get(id)
+			if !ok {
+				http.Error(w, "fault rule not found", http.StatusNotFound)
+
+				return
+			}
+
+			writeJSON(w, http.StatusOK, rule)
+		case r.Method == http.MethodDelete:
+			if !rules.delete(id) {
+				http.Error(w, "fault rule not found", http.StatusNotFound)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// writeJSON writes the value as the JSON response body with the status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
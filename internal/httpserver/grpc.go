@@ -0,0 +1,153 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	whoamiv1 "github.com/andymarkow/whoami/api/whoami/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// grpcDefaultStreamInterval is the default interval of the Whoami.Stream responses.
+const grpcDefaultStreamInterval = time.Second
+
+// newGRPCServer creates a gRPC server with the Whoami, health and reflection services.
+// The values of the redactHeaders metadata keys are redacted in the responses.
+func newGRPCServer(healthServer *health.Server, hostInfo *hostInfoProvider, redactHeaders []string) *grpc.Server {
+	srv := grpc.NewServer()
+
+	whoamiv1.RegisterWhoamiServer(srv, &whoamiService{hostInfo: hostInfo, redactHeaders: redactHeaders})
+	healthpb.RegisterHealthServer(srv, healthServer)
+	reflection.Register(srv)
+
+	return srv
+}
+
+// newGRPCHealthServer creates a gRPC health server reporting the given HTTP health status.
+func newGRPCHealthServer(httpStatus int) *health.Server {
+	healthServer := health.NewServer()
+	setGRPCHealthStatus(healthServer, httpStatus)
+
+	return healthServer
+}
+
+// setGRPCHealthStatus sets the serving status of the server and the Whoami service
+// from the HTTP health status. 2xx and 3xx statuses are reported as serving.
+func setGRPCHealthStatus(healthServer *health.Server, httpStatus int) {
+	servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+	if httpStatus >= http.StatusOK && httpStatus < http.StatusBadRequest {
+		servingStatus = healthpb.HealthCheckResponse_SERVING
+	}
+
+	healthServer.SetServingStatus("", servingStatus)
+	healthServer.SetServingStatus(whoamiv1.Whoami_ServiceDesc.ServiceName, servingStatus)
+}
+
+// whoamiService implements the whoami.v1.Whoami gRPC service.
+type whoamiService struct {
+	whoamiv1.UnimplementedWhoamiServer
+
+	hostInfo      *hostInfoProvider
+	redactHeaders []string
+}
+
+// Get returns the server and the client details.
+func (s *whoamiService) Get(ctx context.Context, req *whoamiv1.GetRequest) (*whoamiv1.GetResponse, error) {
+	return s.newResponse(ctx, req.GetMessage(), 0), nil
+}
+
+// Stream sends the server and the client details every interval, starting immediately,
+// until count responses are sent or the client cancels the call.
+func (s *whoamiService) Stream(req *whoamiv1.StreamRequest, stream whoamiv1.Whoami_StreamServer) error {
+	interval := grpcDefaultStreamInterval
+	if req.GetInterval() != nil {
+		if err := req.GetInterval().CheckValid(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		interval = req.GetInterval().AsDuration()
+	}
+
+	if interval <= 0 {
+		return status.Error(codes.InvalidArgument, "interval must be positive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for seq := uint64(1); req.GetCount() == 0 || seq <= uint64(req.GetCount()); seq++ {
+		if seq > 1 {
+			select {
+			case <-stream.Context().Done():
+				return status.FromContextError(stream.Context().Err()).Err()
+			case <-ticker.C:
+			}
+		}
+
+		resp := s.newResponse(stream.Context(), req.GetMessage(), seq)
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Echo sends the server and the client details for every request of the stream.
+func (s *whoamiService) Echo(stream whoamiv1.Whoami_EchoServer) error {
+	for seq := uint64(1); ; seq++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		resp := s.newResponse(stream.Context(), req.GetMessage(), seq)
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// newResponse returns the hostname, local IPs, peer address and incoming metadata of the call.
+func (s *whoamiService) newResponse(ctx context.Context, message string, seq uint64) *whoamiv1.GetResponse {
+	host := s.hostInfo.get()
+
+	resp := &whoamiv1.GetResponse{
+		Hostname: host.hostname,
+		Ip:       host.localIPs,
+		Metadata: make(map[string]*whoamiv1.MetadataValues),
+		Message:  message,
+		Seq:      seq,
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		resp.PeerAddress = p.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, v := range md {
+		if isSensitiveHeader(s.redactHeaders, k) {
+			v = redactValues(v)
+		}
+
+		resp.Metadata[k] = &whoamiv1.MetadataValues{Values: v}
+	}
+
+	return resp
+}
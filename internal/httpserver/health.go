@@ -0,0 +1,239 @@
+package httpserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Health probes.
+const (
+	probeLiveness  = "liveness"
+	probeReadiness = "readiness"
+	probeStartup   = "startup"
+)
+
+// Health check statuses.
+const (
+	checkPass = "pass"
+	checkFail = "fail"
+)
+
+type healthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type healthResponse struct {
+	Probe  string        `json:"probe"`
+	Status string        `json:"status"`
+	Code   int           `json:"code"`
+	Checks []healthCheck `json:"checks"`
+}
+
+// healthProbes holds the concurrency-safe state of the liveness, readiness and startup probes.
+//
+// Each probe reports the status code set through its endpoint, 200 by default. The startup and
+// readiness probes also fail until the startup delay has elapsed since the server start, and the
+// readiness probe fails once the server shutdown begins.
+type healthProbes struct {
+	startupDelay      time.Duration
+	onReadinessChange func(status int)
+
+	mu           sync.Mutex
+	statuses     map[string]int
+	startedAt    time.Time // Zero until the server starts.
+	shuttingDown bool
+	startupTimer *time.Timer
+}
+
+// newHealthProbes creates the health probes.
+//
+// Parameters:
+// - startupDelay: The delay after the server start before the startup and readiness probes pass.
+// - onReadinessChange: The function called with the readiness status code when it may have changed.
+//
+// Returns:
+// - *healthProbes: The health probes.
+func newHealthProbes(startupDelay time.Duration, onReadinessChange func(status int)) *healthProbes {
+	return &healthProbes{
+		startupDelay:      startupDelay,
+		onReadinessChange: onReadinessChange,
+		statuses: map[string]int{
+			probeLiveness:  http.StatusOK,
+			probeReadiness: http.StatusOK,
+			probeStartup:   http.StatusOK,
+		},
+	}
+}
+
+// start marks the server as started and schedules the end of the startup delay.
+func (p *healthProbes) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.startedAt = time.Now()
+	p.notify()
+
+	if p.startupDelay > 0 {
+		p.startupTimer = time.AfterFunc(p.startupDelay, func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			p.notify()
+		})
+	}
+}
+
+// shutdown marks the server as shutting down, which fails the readiness probe.
+func (p *healthProbes) shutdown() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.shuttingDown = true
+
+	if p.startupTimer != nil {
+		p.startupTimer.Stop()
+	}
+
+	p.notify()
+}
+
+// setStatus sets the status code reported by the probe.
+func (p *healthProbes) setStatus(probe string, status int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.statuses[probe] = status
+
+	if probe == probeReadiness {
+		p.notify()
+	}
+}
+
+// get returns the state of the probe.
+func (p *healthProbes) get(probe string) *healthResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.evaluate(probe, time.Now())
+}
+
+// notify calls onReadinessChange with the readiness status. It is called with the lock held
+// so the changes are reported in order.
+func (p *healthProbes) notify() {
+	if p.onReadinessChange != nil {
+		p.onReadinessChange(p.evaluate(probeReadiness, time.Now()).Code)
+	}
+}
+
+// evaluate runs the checks of the probe. It must be called with the lock held.
+//
+// The probe answers with its status code if it is a failure one or if all the checks pass, and with 503 otherwise.
+func (p *healthProbes) evaluate(probe string, now time.Time) *healthResponse {
+	code := p.statuses[probe]
+
+	resp := &healthResponse{
+		Probe:  probe,
+		Status: checkPass,
+		Code:   code,
+		Checks: []healthCheck{newHealthCheck("status", isHealthyStatus(code), fmt.Sprintf("status code %d", code))},
+	}
+
+	if probe == probeStartup || probe == probeReadiness {
+		switch remaining := p.startedAt.Add(p.startupDelay).Sub(now); {
+		case p.startedAt.IsZero():
+			resp.Checks = append(resp.Checks, newHealthCheck("startup", false, "server is not started"))
+		case remaining > 0:
+			resp.Checks = append(resp.Checks, newHealthCheck("startup", false,
+				fmt.Sprintf("startup delay ends in %s", remaining.Round(time.Millisecond))))
+		default:
+			resp.Checks = append(resp.Checks, newHealthCheck("startup", true, ""))
+		}
+	}
+
+	if probe == probeReadiness {
+		if p.shuttingDown {
+			resp.Checks = append(resp.Checks, newHealthCheck("shutdown", false, "server is shutting down"))
+		} else {
+			resp.Checks = append(resp.Checks, newHealthCheck("shutdown", true, ""))
+		}
+	}
+
+	for _, check := range resp.Checks {
+		if check.Status == checkFail {
+			resp.Status = checkFail
+
+			if isHealthyStatus(resp.Code) {
+				resp.Code = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	return resp
+}
+
+func newHealthCheck(name string, pass bool, message string) healthCheck {
+	check := healthCheck{Name: name, Status: checkFail, Message: message}
+	if pass {
+		check.Status = checkPass
+	}
+
+	return check
+}
+
+// isHealthyStatus reports whether the status code is a 2xx or 3xx one.
+func isHealthyStatus(status int) bool {
+	return status >= http.StatusOK && status < http.StatusBadRequest
+}
+
+// healthHandler returns the state of the probe and sets its status code on POST requests.
+//
+// Parameters:
+// - probes: The health probes.
+// - probe: The probe served by the handler.
+//
+// Returns:
+// - http.Handler: The probe handler.
+func healthHandler(probes *healthProbes, probe string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+
+				return
+			}
+			defer r.Body.Close()
+
+			if len(body) == 0 {
+				http.Error(w, "post request payload required", http.StatusBadRequest)
+
+				return
+			}
+
+			status, err := strconv.Atoi(string(body))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			} else if status < 100 || status > 599 {
+				http.Error(w, fmt.Sprintf("invalid status code: %d", status), http.StatusBadRequest)
+
+				return
+			}
+
+			probes.setStatus(probe, status)
+			w.WriteHeader(http.StatusAccepted)
+
+			return
+		}
+
+		resp := probes.get(probe)
+		writeJSON(w, resp.Code, resp)
+	})
+}
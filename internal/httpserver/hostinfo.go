@@ -0,0 +1,137 @@
+package httpserver
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/andymarkow/whoami/internal/container"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Host information refresh triggers.
+const (
+	hostInfoTriggerInterval = "interval"
+	hostInfoTriggerNetlink  = "netlink"
+)
+
+var promHostInfoRefreshes = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "whoami",
+		Subsystem: "host_info",
+		Name:      "refreshes_total",
+		Help:      "Total number of host information refreshes.",
+	}, []string{"trigger"})
+
+// hostInfo is a snapshot of the host information reported by the endpoints.
+// It is shared by the requests and must not be modified.
+type hostInfo struct {
+	hostname    string
+	interfaces  []interfaceInfo
+	localIPs    []string
+	environment map[string]string
+	kubernetes  *kubernetesInfo
+	container   *container.Info
+}
+
+// hostInfoProvider keeps a snapshot of the hostname, the network interfaces, the environment,
+// the Kubernetes metadata and the container limits so the requests do not query them from
+// the system. The snapshot is taken on creation and refreshed on the interval and, on Linux,
+// when the network addresses or links change.
+type hostInfoProvider struct {
+	interval   time.Duration
+	kubernetes *kubernetesOptions
+	info       atomic.Pointer[hostInfo]
+}
+
+// newHostInfoProvider creates a hostInfoProvider and takes the first snapshot.
+//
+// Parameters:
+// - interval: The interval of the snapshot refreshes. Zero value disables the periodic refreshes.
+// - kubernetes: The sources of the Kubernetes metadata.
+//
+// Returns:
+// - *hostInfoProvider: The host information provider.
+func newHostInfoProvider(interval time.Duration, kubernetes *kubernetesOptions) *hostInfoProvider {
+	p := &hostInfoProvider{interval: interval, kubernetes: kubernetes}
+	p.refresh()
+
+	return p
+}
+
+// get returns the current snapshot.
+func (p *hostInfoProvider) get() *hostInfo {
+	return p.info.Load()
+}
+
+// refresh takes a new snapshot. The hostname of the previous snapshot is kept if it cannot be read.
+func (p *hostInfoProvider) refresh() {
+	info := &hostInfo{
+		interfaces:  getInterfaces(),
+		environment: make(map[string]string),
+	}
+
+	info.localIPs = getLocalIPs(info.interfaces)
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		slog.Error(fmt.Sprintf("os.Hostname: %v", err))
+
+		if prev := p.info.Load(); prev != nil {
+			hostname = prev.hostname
+		}
+	}
+
+	info.hostname = hostname
+
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		info.environment[key] = value
+	}
+
+	info.kubernetes = getKubernetesInfo(info.environment, info.hostname, p.kubernetes)
+	info.container = container.Detect()
+
+	p.info.Store(info)
+}
+
+// Run refreshes the snapshot on the interval and on the network change events until the stop channel is closed.
+func (p *hostInfoProvider) Run(stop <-chan struct{}) {
+	var tick <-chan time.Time
+
+	if p.interval > 0 {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	events, err := watchAddressChanges(stop)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Network changes watch is disabled: %v", err))
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick:
+			p.refresh()
+			promHostInfoRefreshes.WithLabelValues(hostInfoTriggerInterval).Inc()
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+
+				continue
+			}
+
+			p.refresh()
+			promHostInfoRefreshes.WithLabelValues(hostInfoTriggerNetlink).Inc()
+			slog.Debug("Host information refreshed on network change")
+		}
+	}
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchAddressChanges subscribes to the netlink link and address change notifications.
+// The returned channel receives a value per batch of changes and is closed when
+// the stop channel is closed or the socket fails.
+func watchAddressChanges(stop <-chan struct{}) (<-chan struct{}, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("unix.Socket: %w", err)
+	}
+
+	sa := &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	}
+
+	if err := unix.Bind(fd, sa); err != nil {
+		unix.Close(fd)
+
+		return nil, fmt.Errorf("unix.Bind: %w", err)
+	}
+
+	// The non-blocking file is served by the runtime poller so Close unblocks the pending Read.
+	f := os.NewFile(uintptr(fd), "netlink")
+
+	go func() {
+		<-stop
+		f.Close()
+	}()
+
+	events := make(chan struct{}, 1)
+
+	go func() {
+		defer close(events)
+
+		buf := make([]byte, os.Getpagesize())
+
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				// The kernel drops the notifications if the socket buffer overflows, refresh anyway.
+				if errors.Is(err, unix.ENOBUFS) {
+					notify(events)
+
+					continue
+				}
+
+				return
+			}
+
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+
+			for _, msg := range msgs {
+				switch msg.Header.Type {
+				case unix.RTM_NEWADDR, unix.RTM_DELADDR, unix.RTM_NEWLINK, unix.RTM_DELLINK:
+					notify(events)
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// notify sends a value to the channel unless one is already pending.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
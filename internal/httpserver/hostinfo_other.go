@@ -0,0 +1,8 @@
+//go:build !linux
+
+package httpserver
+
+// watchAddressChanges is not supported on this platform, the snapshot is refreshed on the interval only.
+func watchAddressChanges(_ <-chan struct{}) (<-chan struct{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,36 @@
+package httpserver //nolint:testpackage // The benchmark measures the unexported host information provider.
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// BenchmarkGetWhoamiData compares the request path reading the cached host information snapshot
+// with the request path taking a fresh snapshot, as done on each request before the provider.
+func BenchmarkGetWhoamiData(b *testing.B) {
+	cfg := &Config{EnvEnabled: true}
+	provider := newHostInfoProvider(0, &kubernetesOptions{})
+	r := httptest.NewRequest("GET", "/api?env", nil)
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for range b.N {
+			if _, err := getWhoamiData(r, cfg, provider.get()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("refresh", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for range b.N {
+			provider.refresh()
+
+			if _, err := getWhoamiData(r, cfg, provider.get()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// HTTP/2 server settings shared by the HTTPS and h2c listeners.
+const (
+	http2MaxConcurrentStreams         = 250
+	http2MaxReadFrameSize             = 1 << 20
+	http2MaxUploadBufferPerConnection = 1 << 20
+	http2MaxUploadBufferPerStream     = 1 << 20
+)
+
+// HTTP/2 cleartext connection modes.
+const (
+	h2cPriorKnowledge = "prior-knowledge"
+	h2cUpgrade        = "upgrade"
+)
+
+// h2cModeKey is the connection context key of the h2c connection mode.
+type h2cModeKey struct{}
+
+type protocolInfo struct {
+	Major             int                  `json:"major"`
+	Minor             int                  `json:"minor"`
+	H2C               string               `json:"h2c,omitempty"`
+	ConnectionRequest int64                `json:"connection_request,omitempty"`
+	HTTP2Server       *http2ServerSettings `json:"http2_server_settings,omitempty"`
+	QUIC              *quicInfo            `json:"quic,omitempty"`
+}
+
+type quicInfo struct {
+	Version           string `json:"version"`
+	Used0RTT          bool   `json:"used_0rtt"`
+	SupportsDatagrams bool   `json:"supports_datagrams"`
+	LocalAddr         string `json:"local_addr"`
+	RemoteAddr        string `json:"remote_addr"`
+}
+
+// http2ServerSettings are the HTTP/2 settings of the server, the same for all the connections.
+// They are not the values negotiated on the connection of the request.
+type http2ServerSettings struct {
+	MaxConcurrentStreams    uint32 `json:"max_concurrent_streams"`
+	MaxReadFrameSize        uint32 `json:"max_read_frame_size"`
+	InitialConnWindowSize   int32  `json:"initial_conn_window_size"`
+	InitialStreamWindowSize int32  `json:"initial_stream_window_size"`
+}
+
+func newHTTP2Server() *http2.Server {
+	return &http2.Server{
+		MaxConcurrentStreams:         http2MaxConcurrentStreams,
+		MaxReadFrameSize:             http2MaxReadFrameSize,
+		MaxUploadBufferPerConnection: http2MaxUploadBufferPerConnection,
+		MaxUploadBufferPerStream:     http2MaxUploadBufferPerStream,
+	}
+}
+
+// supportsHTTP2 reports whether HTTP/2 can be served with the TLS config. HTTP/2 requires one of
+// the TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 and TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 cipher suites
+// if the TLS 1.2 cipher suites are restricted.
+func supportsHTTP2(cfg *tls.Config) bool {
+	if cfg.CipherSuites == nil || cfg.MinVersion >= tls.VersionTLS13 {
+		return true
+	}
+
+	return slices.ContainsFunc(cfg.CipherSuites, func(id uint16) bool {
+		return id == tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 || id == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
+	})
+}
+
+// withoutHTTP2 returns a copy of the TLS config which does not negotiate HTTP/2,
+// including in the per-client configs.
+func withoutHTTP2(cfg *tls.Config) *tls.Config {
+	removeH2 := func(c *tls.Config) {
+		c.NextProtos = slices.DeleteFunc(slices.Clone(c.NextProtos), func(proto string) bool {
+			return proto == http2.NextProtoTLS
+		})
+	}
+
+	c := cfg.Clone()
+	removeH2(c)
+
+	if getConfig := cfg.GetConfigForClient; getConfig != nil {
+		c.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			clientCfg, err := getConfig(hello)
+			if err != nil || clientCfg == nil {
+				return clientCfg, err
+			}
+
+			clientCfg = clientCfg.Clone()
+			removeH2(clientCfg)
+
+			return clientCfg, nil
+		}
+	}
+
+	return c
+}
+
+// h2cHandler wraps the handler to accept HTTP/2 over cleartext connections
+// both with prior knowledge and with the HTTP/1.1 Upgrade mechanism.
+// The connection mode is stored in the context of the requests served over the connection.
+func h2cHandler(next http.Handler) http.Handler {
+	h := h2c.NewHandler(next, newHTTP2Server())
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var mode string
+
+		switch {
+		case r.Method == "PRI" && r.URL.Path == "*" && r.Proto == "HTTP/2.0":
+			mode = h2cPriorKnowledge
+		case isH2CUpgrade(r.Header):
+			mode = h2cUpgrade
+		}
+
+		if mode != "" {
+			r = r.WithContext(context.WithValue(r.Context(), h2cModeKey{}, mode))
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// isH2CUpgrade reports whether the request headers ask for an upgrade to HTTP/2 over cleartext.
+func isH2CUpgrade(h http.Header) bool {
+	if h.Get("HTTP2-Settings") == "" {
+		return false
+	}
+
+	for _, v := range h.Values("Upgrade") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "h2c") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getProtocolInfo returns the HTTP protocol details of the request.
+func getProtocolInfo(r *http.Request) *protocolInfo {
+	info := &protocolInfo{
+		Major:             r.ProtoMajor,
+		Minor:             r.ProtoMinor,
+		ConnectionRequest: getConnectionRequest(r.Context()),
+	}
+
+	if mode, ok := r.Context().Value(h2cModeKey{}).(string); ok {
+		info.H2C = mode
+	}
+
+	if r.ProtoMajor == 2 {
+		info.HTTP2Server = &http2ServerSettings{
+			MaxConcurrentStreams:    http2MaxConcurrentStreams,
+			MaxReadFrameSize:        http2MaxReadFrameSize,
+			InitialConnWindowSize:   http2MaxUploadBufferPerConnection,
+			InitialStreamWindowSize: http2MaxUploadBufferPerStream,
+		}
+	}
+
+	if conn := getConnInfo(r.Context()); conn != nil && conn.quicConn != nil {
+		state := conn.quicConn.ConnectionState()
+
+		info.QUIC = &quicInfo{
+			Version:           state.Version.String(),
+			Used0RTT:          state.Used0RTT,
+			SupportsDatagrams: state.SupportsDatagrams,
+			LocalAddr:         conn.quicConn.LocalAddr().String(),
+			RemoteAddr:        conn.quicConn.RemoteAddr().String(),
+		}
+	}
+
+	return info
+}
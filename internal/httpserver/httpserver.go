@@ -3,24 +3,27 @@ package httpserver
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"net"
+	"log/slog"
 	"net/http"
-	"os"
+	"net/http/pprof"
+	"net/netip"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	httpmetrics "github.com/slok/go-http-metrics/metrics"
 	metrics "github.com/slok/go-http-metrics/metrics/prometheus"
 	"github.com/slok/go-http-metrics/middleware"
 	"github.com/slok/go-http-metrics/middleware/std"
 	"github.com/urfave/negroni"
+	"google.golang.org/grpc/health"
 )
 
 // Data size units.
@@ -32,10 +35,18 @@ const (
 	TB
 )
 
-var healthStatus = 200
-
 type Config struct {
 	ServerAddr         string
+	TLSServerAddr      string // Serves HTTPS on a separate address if set, plain HTTP is served on ServerAddr.
+	AdminAddr          string // Serves operational endpoints on a separate address if set.
+	GRPCAddr           string // Serves gRPC on a separate address if set.
+	TCPEchoAddr        string // Serves the TCP echo on a separate address if set.
+	UDPEchoAddr        string // Serves the UDP echo on a separate address if set.
+	ProxyProtocol      bool   // Accepts the PROXY protocol on the public TCP listeners.
+	ProxyTrustedCIDRs  []netip.Prefix
+	TrustedProxies     []netip.Prefix // Networks of the proxies trusted to set the forwarding headers.
+	H2C                bool
+	HTTP3              bool // Serves HTTP/3 over QUIC on the UDP port of the HTTPS address.
 	AccessLogEnabled   bool
 	AccessLogSkipPaths []string
 	ReadTimeout        time.Duration
@@ -43,20 +54,48 @@ type Config struct {
 	WriteTimeout       time.Duration
 	TLSCrtFile         string
 	TLSKeyFile         string
+	TLSCertPairs       []string // List of "cert:key" file pairs.
+	TLSCertDir         string
 	TLSCAFile          string
+	TLSClientAuth      string // Possible values: none, request, require, verify-if-given, require-and-verify.
+	TLSMinVersion      string // Possible values: 1.0, 1.1, 1.2, 1.3.
+	TLSMaxVersion      string // Possible values: 1.0, 1.1, 1.2, 1.3.
+	TLSCipherSuites    []string
+	TLSCurves          []string
+	TLSReloadInterval  time.Duration
+	TLSSelfSigned      bool
+	TLSSelfSignedSANs  []string
+	TLSSelfSignedCA    string
+	MaxBodySize        int64
+	HostInfoRefresh    time.Duration // Host information refresh interval, 0 disables the periodic refreshes.
+	EnvEnabled         bool          // Reports the environment variables on the "env" query parameter.
+	EnvAllow           []string      // Glob patterns of the reported environment variable names, all if empty.
+	EnvDeny            []string      // Glob patterns of the hidden environment variable names.
+	EnvRedact          []string      // Glob patterns of the environment variable names with redacted values.
+	RedactHeaders      []string      // Names of the request headers with redacted values.
+	K8sPodInfoDir      string        // Directory of the downward API labels and annotations files.
+	K8sNodeNameEnv     string        // Environment variable of the node name.
+	K8sPodIPEnv        string        // Environment variable of the pod IP.
+	K8sZoneEnv         string        // Environment variable of the node zone.
+	FaultDefaults      url.Values    // Server-side fault injection parameters.
+	StartupDelay       time.Duration // Delay after the start before the startup and readiness probes pass.
+	ShutdownDelay      time.Duration // Delay between the readiness failure and the listeners shutdown.
 }
 
 type Server struct {
-	server      *http.Server
-	tlsCertFile string
-	tlsKeyFile  string
-	tlsCAFile   string
+	listeners     []*listener
+	hostInfo      *hostInfoProvider
+	health        *healthProbes
+	shutdownDelay time.Duration
+	tlsOptions    *tlsOptions // Nil if TLS is disabled.
+	stop          chan struct{}
 }
 
 type jsonResponse struct {
 	RequestID   string              `json:"request_id"`
 	Hostname    string              `json:"hostname"`
 	IP          []string            `json:"ip"`
+	Interfaces  []interfaceInfo     `json:"interfaces"`
 	Host        string              `json:"host"`
 	URL         string              `json:"url"`
 	Params      map[string][]string `json:"params,omitempty"`
@@ -66,116 +105,283 @@ type jsonResponse struct {
 	UserAgent   string              `json:"user_agent"`
 	RemoteAddr  string              `json:"remote_addr"`
 	Environment map[string]string   `json:"environment,omitempty"`
+	Body        *bodyInfo           `json:"body,omitempty"`
+	TLS         *tlsInfo            `json:"tls,omitempty"`
+	Protocol    *protocolInfo       `json:"protocol"`
+	Proxy       *proxyProtocolInfo  `json:"proxy_protocol,omitempty"`
+	Client      *clientInfo         `json:"client"`
+	Kubernetes  *kubernetesInfo     `json:"kubernetes,omitempty"`
+	Runtime     *runtimeInfo        `json:"runtime"`
 }
 
+// httpMetricsRecorder returns the recorder of the HTTP request metrics. The metrics are registered
+// once so the servers created in the same process share them.
+var httpMetricsRecorder = sync.OnceValue(func() httpmetrics.Recorder {
+	return metrics.NewRecorder(metrics.Config{
+		HandlerIDLabel: "path",
+		DurationBuckets: []float64{
+			0.05, // 50ms
+			0.1,  // 100ms
+			0.5,  // 500ms
+			1,    // 1s
+			2.5,  // 2.5s
+			5,    // 5s
+			10,   // 10s
+		},
+	})
+})
+
 // NewHTTPServer creates a new HTTP server with the given configuration.
 //
 // It takes a pointer to a Config struct as a parameter.
-// It returns a pointer to an httpServer struct.
-func NewServer(cfg *Config) *Server {
+// It returns a pointer to an httpServer struct or an error if the fault injection defaults are invalid.
+func NewServer(cfg *Config) (*Server, error) {
 	mux := http.NewServeMux()
 
-	mux.Handle("/metrics", useMiddleware(promhttp.Handler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
-	mux.Handle("/health", useMiddleware(healthHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
-	mux.Handle("/upload", useMiddleware(uploadHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
-	mux.Handle("/data", useMiddleware(dataHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
-	mux.Handle("/api/", useMiddleware(apiHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
-	mux.Handle("/api", useMiddleware(apiHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
-	mux.Handle("/", useMiddleware(whoamiHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
+	// Operational endpoints are served by the admin listener if it is enabled.
+	adminMux := mux
+	if cfg.AdminAddr != "" {
+		adminMux = http.NewServeMux()
+
+		// Keep the catch-all whoami route from answering on the moved paths.
+		for _, path := range []string{"/metrics", "/health", "/livez", "/readyz", "/startupz", "/faults", "/faults/", "/debug/pprof/"} {
+			mux.Handle(path, useMiddleware(http.NotFoundHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
+		}
+
+		adminMux.HandleFunc("/debug/pprof/", pprof.Index)
+		adminMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		adminMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		adminMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		adminMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	// The stop channel is closed when the server shutdown begins, after the shutdown delay.
+	stop := make(chan struct{})
+
+	hostInfo := newHostInfoProvider(cfg.HostInfoRefresh, &kubernetesOptions{
+		podInfoDir:  cfg.K8sPodInfoDir,
+		nodeNameEnv: cfg.K8sNodeNameEnv,
+		podIPEnv:    cfg.K8sPodIPEnv,
+		zoneEnv:     cfg.K8sZoneEnv,
+	})
+
+	adminMux.Handle("/metrics", useMiddleware(promhttp.Handler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
+	// The gRPC health service reports the readiness status.
+	var grpcHealth *health.Server
+
+	probes := newHealthProbes(cfg.StartupDelay, func(status int) {
+		if grpcHealth != nil {
+			setGRPCHealthStatus(grpcHealth, status)
+		}
+	})
+
+	if cfg.GRPCAddr != "" {
+		grpcHealth = newGRPCHealthServer(probes.get(probeReadiness).Code)
+	}
+
+	for path, probe := range map[string]string{
+		"/livez":    probeLiveness,
+		"/readyz":   probeReadiness,
+		"/startupz": probeStartup,
+		"/health":   probeReadiness, // Kept for compatibility.
+	} {
+		adminMux.Handle(path, useMiddleware(healthHandler(probes, probe), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
+	}
+
+	if _, err := parseFaultSpec(cfg.FaultDefaults.Get); err != nil {
+		return nil, fmt.Errorf("parseFaultSpec: %w", err)
+	}
+
+	faultRules := newFaultRules(cfg.TrustedProxies)
+
+	// The fault rules apply to all the clients, so they are managed on the admin listener only, as pprof.
+	if cfg.AdminAddr != "" {
+		adminMux.Handle("/faults", useMiddleware(faultRulesHandler(faultRules), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
+		adminMux.Handle("/faults/", useMiddleware(faultRulesHandler(faultRules), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
+	} else {
+		// Keep the catch-all whoami route from answering on the fault rules paths.
+		for _, path := range []string{"/faults", "/faults/"} {
+			mux.Handle(path, useMiddleware(http.NotFoundHandler(), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths))
+		}
+
+		slog.Info("Fault rules API is disabled, it requires the admin listener")
+	}
+
+	// The application routes inject the faults, the operational endpoints are not faulted.
+	route := func(h http.Handler) http.Handler {
+		return useMiddleware(faultHandler(h, cfg.FaultDefaults, faultRules), cfg.AccessLogEnabled, cfg.AccessLogSkipPaths)
+	}
+
+	mux.Handle("/upload", route(uploadHandler()))
+	mux.Handle("/data", route(dataHandler()))
+	mux.Handle("/ws", route(wsHandler(cfg, hostInfo)))
+	mux.Handle("/sse", route(sseHandler(hostInfo, stop)))
+	mux.Handle("/api/", route(whoamiHandler(cfg, hostInfo, formatJSON)))
+	mux.Handle("/api", route(whoamiHandler(cfg, hostInfo, formatJSON)))
+	mux.Handle("/", route(whoamiHandler(cfg, hostInfo, formatText)))
 
 	metricsMW := middleware.New(middleware.Config{
-		Recorder: metrics.NewRecorder(metrics.Config{
-			HandlerIDLabel: "path",
-			DurationBuckets: []float64{
-				0.05, // 50ms
-				0.1,  // 100ms
-				0.5,  // 500ms
-				1,    // 1s
-				2.5,  // 2.5s
-				5,    // 5s
-				10,   // 10s
-			},
-		}),
+		Recorder: httpMetricsRecorder(),
 	})
 
-	h := std.Handler("", metricsMW, mux)
+	h := countRequests(hijackGuard(withResponseController(std.Handler("", metricsMW, mux))))
+	adminHandler := countRequests(std.Handler("", metricsMW, adminMux))
+
+	// Plain HTTP listeners accept HTTP/2 over cleartext if enabled.
+	plainHandler := h
+	if cfg.H2C {
+		plainHandler = h2cHandler(h)
+	}
+
+	srv := &Server{
+		hostInfo:      hostInfo,
+		health:        probes,
+		shutdownDelay: cfg.ShutdownDelay,
+		stop:          stop,
+	}
+
+	if cfg.TLSCrtFile != "" || cfg.TLSKeyFile != "" || len(cfg.TLSCertPairs) > 0 || cfg.TLSCertDir != "" || cfg.TLSSelfSigned {
+		srv.tlsOptions = &tlsOptions{
+			certFile:         cfg.TLSCrtFile,
+			keyFile:          cfg.TLSKeyFile,
+			certPairs:        cfg.TLSCertPairs,
+			certDir:          cfg.TLSCertDir,
+			caFile:           cfg.TLSCAFile,
+			clientAuth:       cfg.TLSClientAuth,
+			minVersion:       cfg.TLSMinVersion,
+			maxVersion:       cfg.TLSMaxVersion,
+			cipherSuites:     cfg.TLSCipherSuites,
+			curves:           cfg.TLSCurves,
+			reloadInterval:   cfg.TLSReloadInterval,
+			selfSigned:       cfg.TLSSelfSigned,
+			selfSignedSANs:   cfg.TLSSelfSignedSANs,
+			selfSignedCAFile: cfg.TLSSelfSignedCA,
+		}
+	}
+
+	httpsAddr := cfg.TLSServerAddr
+	if httpsAddr == "" {
+		httpsAddr = cfg.ServerAddr
+	}
+
+	httpsHandler := h
+
+	if cfg.HTTP3 && srv.tlsOptions == nil {
+		slog.Warn("HTTP/3 requires TLS to be enabled, the HTTP/3 listener is disabled")
+	} else if cfg.HTTP3 {
+		h3 := newHTTP3Listener(httpsAddr, h)
+		httpsHandler = altSvcHandler(h3.quic, h)
 
-	srv := &http.Server{
-		Addr:              cfg.ServerAddr,
-		Handler:           h,
-		ReadTimeout:       cfg.ReadTimeout,
-		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
-		WriteTimeout:      cfg.WriteTimeout,
+		srv.listeners = append(srv.listeners, h3)
 	}
 
-	return &Server{
-		server:      srv,
-		tlsCertFile: cfg.TLSCrtFile,
-		tlsKeyFile:  cfg.TLSKeyFile,
-		tlsCAFile:   cfg.TLSCAFile,
+	switch {
+	case srv.tlsOptions == nil:
+		srv.listeners = append(srv.listeners, newListener(listenerHTTP, cfg.ServerAddr, plainHandler, cfg))
+	case cfg.TLSServerAddr == "":
+		srv.listeners = append(srv.listeners, newListener(listenerHTTPS, cfg.ServerAddr, httpsHandler, cfg))
+	default:
+		srv.listeners = append(srv.listeners,
+			newListener(listenerHTTP, cfg.ServerAddr, plainHandler, cfg),
+			newListener(listenerHTTPS, cfg.TLSServerAddr, httpsHandler, cfg),
+		)
 	}
+
+	if cfg.GRPCAddr != "" {
+		srv.listeners = append(srv.listeners, newGRPCListener(cfg.GRPCAddr, newGRPCServer(grpcHealth, hostInfo, cfg.RedactHeaders)))
+	}
+
+	var proxyProtocol *proxyProtocolOptions
+	if cfg.ProxyProtocol {
+		proxyProtocol = &proxyProtocolOptions{trustedCIDRs: cfg.ProxyTrustedCIDRs}
+	}
+
+	// The PROXY protocol is accepted by the public TCP listeners only.
+	for _, l := range srv.listeners {
+		if l.server != nil || l.grpc != nil {
+			l.proxyProtocol = proxyProtocol
+		}
+	}
+
+	if cfg.TCPEchoAddr != "" {
+		srv.listeners = append(srv.listeners, newEchoListener(echoTCP, cfg.TCPEchoAddr, proxyProtocol, hostInfo))
+	}
+
+	if cfg.UDPEchoAddr != "" {
+		srv.listeners = append(srv.listeners, newEchoListener(echoUDP, cfg.UDPEchoAddr, nil, hostInfo))
+	}
+
+	if cfg.AdminAddr != "" {
+		srv.listeners = append(srv.listeners, newListener(listenerAdmin, cfg.AdminAddr, adminHandler, cfg))
+	}
+
+	return srv, nil
 }
 
-// Start starts the HTTP server.
+// Start starts all the server listeners and blocks until they are stopped.
 //
-// It does not take any parameters.
-// It returns an error.
+// It returns an error if the TLS configuration is invalid or any of the listeners fails to start.
 func (s *Server) Start() error {
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("server.ListenAndServe: %w", err)
-	}
+	var tlsConfig *tls.Config
 
-	return nil
-}
+	if s.tlsOptions != nil {
+		slog.Info("TLS enabled")
 
-func (s *Server) StartTLS() error {
-	if s.tlsCAFile != "" {
 		var err error
-		if s.server.TLSConfig, err = getTLSConfig(s.tlsCAFile); err != nil {
-			return fmt.Errorf("getTLSConfig: %w", err)
+		if tlsConfig, err = newServerTLSConfig(s.tlsOptions, s.stop); err != nil {
+			return fmt.Errorf("newServerTLSConfig: %w", err)
 		}
 	}
 
-	if err := s.server.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile); err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("server.ListenAndServeTLS: %w", err)
+	go s.hostInfo.Run(s.stop)
+
+	s.health.start()
+
+	errCh := make(chan error, len(s.listeners))
+
+	for _, l := range s.listeners {
+		go func(l *listener) {
+			errCh <- l.serve(tlsConfig)
+		}(l)
+	}
+
+	for range s.listeners {
+		if err := <-errCh; err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// Shutdown shuts down the HTTP server.
+// Shutdown shuts down all the server listeners.
 //
-// It uses a context with a timeout of 5 seconds to gracefully shutdown the server.
-// It returns an error if the server fails to shutdown.
+// It fails the readiness probe, keeps serving for the shutdown delay so the probes and the load balancers
+// observe the failure, then uses a context with a timeout of 5 seconds to gracefully shutdown the server.
+// It returns an error if any of the listeners fails to shutdown.
 func (s *Server) Shutdown() error {
+	// Fail the readiness probe first so the load balancers stop sending new requests.
+	s.health.shutdown()
+
+	if s.shutdownDelay > 0 {
+		slog.Info(fmt.Sprintf("Waiting %s before closing the listeners", s.shutdownDelay))
+		time.Sleep(s.shutdownDelay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := s.server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server.Shutdown: %w", err)
-	}
+	close(s.stop)
 
-	return nil
-}
+	var errs []error
 
-// getTLSConfig returns a tls.Config and an error. It reads the CA certificate
-// from the specified file and creates a tls.Config object with the client
-// authentication type, client certificate authority, and minimum TLS version.
-// This is used to configure the server's mutual TLS configuration.
-func getTLSConfig(caFile string) (*tls.Config, error) {
-	caCrt, err := os.ReadFile(caFile)
-	if err != nil {
-		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	for _, l := range s.listeners {
+		if err := l.shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s listener shutdown: %w", l.name, err))
+		}
 	}
 
-	caCrtPool := x509.NewCertPool()
-	caCrtPool.AppendCertsFromPEM(caCrt)
-
-	return &tls.Config{
-		ClientAuth: tls.RequireAndVerifyClientCert,
-		ClientCAs:  caCrtPool,
-		MinVersion: tls.VersionTLS12,
-	}, nil
+	return errors.Join(errs...)
 }
 
 // skipURLPath checks if the given path should be skipped based on a list of excluded paths.
@@ -207,9 +413,13 @@ func useMiddleware(next http.Handler, withAccessLog bool, pathExcludes []string)
 		}
 
 		rw := negroni.NewResponseWriter(w)
-		next.ServeHTTP(rw, r)
 
-		if withAccessLog && !skipURLPath(r.URL.Path, pathExcludes) {
+		// The access log is written when the handler returns or panics to abort the response.
+		defer func() {
+			if !withAccessLog || skipURLPath(r.URL.Path, pathExcludes) {
+				return
+			}
+
 			fmt.Printf(
 				`{"time":"%s","request_id":"%s","remote_ip":"%s",`+
 					`"host":"%s","method":"%s","uri":"%s","status":%d,`+
@@ -228,47 +438,9 @@ func useMiddleware(next http.Handler, withAccessLog bool, pathExcludes []string)
 				r.ContentLength,
 				rw.Size(),
 			)
-		}
-	})
-}
+		}()
 
-func healthHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			body, err := io.ReadAll(r.Body)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-
-				return
-			}
-			defer r.Body.Close()
-
-			if len(body) == 0 {
-				http.Error(w, "post request payload required", http.StatusBadRequest)
-
-				return
-			}
-
-			status, err := strconv.Atoi(string(body))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-
-				return
-			} else if status < 100 || status > 599 {
-				http.Error(w, fmt.Sprintf("invalid status code: %d", status), http.StatusBadRequest)
-
-				return
-			}
-
-			healthStatus = status
-			w.WriteHeader(http.StatusAccepted)
-
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(healthStatus)
-		fmt.Fprintf(w, `{"status":%d}`, healthStatus)
+		next.ServeHTTP(rw, r)
 	})
 }
 
@@ -349,114 +521,23 @@ func dataHandler() http.Handler {
 	})
 }
 
-func apiHandler() http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Query().Has("delay") {
-			duration, err := time.ParseDuration(r.URL.Query().Get("delay"))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-
-				return
-			}
-			time.Sleep(duration)
-		}
-
-		data, err := getWhoamiData(r)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(data); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-
-			return
-		}
-	})
-}
-
-func whoamiHandler() http.Handler {
+// whoamiHandler returns the whoami data in the format negotiated by the
+// "format" query parameter or the Accept header, falling back to defaultFormat.
+func whoamiHandler(cfg *Config, hostInfo *hostInfoProvider, defaultFormat string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Query().Has("delay") {
-			duration, err := time.ParseDuration(r.URL.Query().Get("delay"))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-
-				return
-			}
-			time.Sleep(duration)
-		}
-
-		data, err := getWhoamiData(r)
+		data, err := getWhoamiData(r, cfg, hostInfo.get())
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
 			return
 		}
 
-		resp := fmt.Sprintf(`RequestID: %s
-Hostname: %s
-IP: %s
-Host: %s
-URL: %s
-Method: %s
-Proto: %s
-Params: %v
-Headers: %v
-UserAgent: %s
-RemoteAddr: %s
-Environment: %s
-`,
-			data.RequestID,
-			data.Hostname,
-			data.IP,
-			data.Host,
-			data.URL,
-			data.Method,
-			data.Proto,
-			data.Params,
-			data.Headers,
-			data.UserAgent,
-			data.RemoteAddr,
-			data.Environment,
-		)
-
-		fmt.Fprintln(w, resp)
+		writeResponse(w, r, data, defaultFormat)
 	})
 }
 
-func getWhoamiData(r *http.Request) (*jsonResponse, error) {
-	hostname, err := os.Hostname()
-	if err != nil {
-		return nil, fmt.Errorf("os.Hostname: %w", err)
-	}
-
-	var localIPs []string
-
-	ifaces, _ := net.Interfaces()
-	for _, iface := range ifaces {
-		addrs, _ := iface.Addrs()
-		for _, addr := range addrs {
-			ipnet, ok := addr.(*net.IPNet)
-			if !ok {
-				continue
-			}
-			v4 := ipnet.IP.To4()
-			if v4 == nil || v4[0] == 127 { // loopback address
-				continue
-			}
-			localIPs = append(localIPs, v4.String())
-		}
-	}
-
-	remoteAddr := r.Header.Get("X-Forwarded-For")
-	if remoteAddr == "" {
-		remoteAddr = r.RemoteAddr
-	}
-
+// getWhoamiData collects the whoami data of the request. The host details are read from the snapshot.
+func getWhoamiData(r *http.Request, cfg *Config, host *hostInfo) (*jsonResponse, error) {
 	params := make(map[string][]string)
 	for k, v := range r.URL.Query() {
 		params[k] = v
@@ -464,27 +545,53 @@ func getWhoamiData(r *http.Request) (*jsonResponse, error) {
 
 	requestID := r.Header.Get("X-Request-Id")
 
-	environment := make(map[string]string)
+	var environment map[string]string
+	if cfg.EnvEnabled && r.URL.Query().Has("env") {
+		environment = filterEnvironment(host.environment, cfg)
+	}
 
-	if r.URL.Query().Has("env") {
-		for _, env := range os.Environ() {
-			split := strings.SplitN(env, "=", 2)
-			environment[split[0]] = split[1]
-		}
+	body, err := getBodyInfo(r, cfg.MaxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("getBodyInfo: %w", err)
 	}
 
 	return &jsonResponse{
 		RequestID:   requestID,
-		Hostname:    hostname,
-		IP:          localIPs,
+		Hostname:    host.hostname,
+		IP:          host.localIPs,
+		Interfaces:  filterInterfaces(host.interfaces, parseInterfaceFilter(r.URL.Query())),
 		Host:        r.Host,
 		URL:         r.RequestURI,
 		Params:      params,
 		Method:      r.Method,
 		Proto:       r.Proto,
-		Headers:     r.Header.Clone(),
+		Headers:     redactHeaders(r.Header, cfg.RedactHeaders),
 		UserAgent:   r.UserAgent(),
-		RemoteAddr:  remoteAddr,
+		RemoteAddr:  r.RemoteAddr,
 		Environment: environment,
+		Body:        body,
+		TLS:         getTLSInfo(r),
+		Protocol:    getProtocolInfo(r),
+		Proxy:       getProxyProtocolInfo(getProxyProtocolConn(r.Context())),
+		Client:      getClientInfo(r, cfg.TrustedProxies),
+		Kubernetes:  host.kubernetes,
+		Runtime:     getRuntimeInfo(host.container),
 	}, nil
 }
+
+// getLocalIPs returns the non-loopback IPv4 addresses of the host network interfaces.
+// It is kept for the legacy "ip" field, see the "interfaces" field for the full details.
+func getLocalIPs(ifaces []interfaceInfo) []string {
+	var localIPs []string
+
+	for _, iface := range ifaces {
+		for _, addr := range iface.Addresses {
+			if addr.Family != "4" || addr.Scope == scopeHost {
+				continue
+			}
+			localIPs = append(localIPs, addr.IP)
+		}
+	}
+
+	return localIPs
+}
@@ -0,0 +1,91 @@
+package httpserver_test
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andymarkow/whoami/internal/httpserver"
+)
+
+// startServer starts the server on a free local port and returns its address and shutdown function.
+// The server is shut down at the end of the test if it is still running.
+func startServer(t *testing.T, cfg *httpserver.Config) (string, func() error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	cfg.ServerAddr = addr
+
+	srv, err := httpserver.NewServer(cfg)
+	if err != nil {
+		t.Fatalf("httpserver.NewServer: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- srv.Start()
+	}()
+
+	shutdown := sync.OnceValue(srv.Shutdown)
+	t.Cleanup(func() { _ = shutdown() })
+
+	// Wait for the listener to accept connections or the server to fail.
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		select {
+		case err := <-errCh:
+			t.Fatalf("srv.Start: %v", err)
+		default:
+		}
+
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			_ = conn.Close()
+
+			return addr, shutdown
+		}
+	}
+
+	t.Fatalf("server did not start on %s", addr)
+
+	return "", nil
+}
+
+func TestHTTPSWithoutHTTP2CipherSuite(t *testing.T) {
+	addr, _ := startServer(t, &httpserver.Config{
+		TLSSelfSigned:   true,
+		TLSMaxVersion:   "1.2",
+		TLSCipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+	})
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // Self-signed test certificate.
+			ForceAttemptHTTP2: true,
+		},
+	}
+
+	resp, err := client.Get("https://" + addr + "/api")
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if resp.ProtoMajor != 1 {
+		t.Errorf("proto = %s, want HTTP/1.1", resp.Proto)
+	}
+}
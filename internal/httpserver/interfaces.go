@@ -0,0 +1,178 @@
+package httpserver
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// Address scopes.
+const (
+	scopeHost      = "host"
+	scopeLinkLocal = "link"
+	scopePrivate   = "private"
+	scopeGlobal    = "global"
+)
+
+type interfaceInfo struct {
+	Name      string             `json:"name"`
+	Index     int                `json:"index"`
+	MAC       string             `json:"mac,omitempty"`
+	MTU       int                `json:"mtu"`
+	Flags     []string           `json:"flags"`
+	Addresses []interfaceAddress `json:"addresses"`
+}
+
+type interfaceAddress struct {
+	IP        string `json:"ip"`
+	PrefixLen int    `json:"prefix_len"`
+	Family    string `json:"family"`
+	Scope     string `json:"scope"`
+}
+
+// interfaceFilter selects the reported interfaces and addresses.
+// The zero value selects everything.
+type interfaceFilter struct {
+	names  []string // Interface names.
+	family string   // Address family: 4 or 6.
+	scope  string   // Address scope: host, link, private or global.
+}
+
+// parseInterfaceFilter reads the filter from the "iface", "family" and "scope" query parameters.
+// The "iface" parameter may be repeated or hold a comma separated list of names.
+func parseInterfaceFilter(query url.Values) interfaceFilter {
+	var names []string
+
+	for _, value := range query["iface"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return interfaceFilter{
+		names:  names,
+		family: strings.TrimPrefix(strings.ToLower(query.Get("family")), "ipv"),
+		scope:  strings.ToLower(query.Get("scope")),
+	}
+}
+
+// getInterfaces returns the host network interfaces with all their IPv4 and IPv6 addresses.
+func getInterfaces() []interfaceInfo {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	result := make([]interfaceInfo, 0, len(ifaces))
+
+	for _, iface := range ifaces {
+		info := interfaceInfo{
+			Name:      iface.Name,
+			Index:     iface.Index,
+			MAC:       iface.HardwareAddr.String(),
+			MTU:       iface.MTU,
+			Flags:     strings.Split(iface.Flags.String(), "|"),
+			Addresses: []interfaceAddress{},
+		}
+
+		if iface.Flags == 0 {
+			info.Flags = []string{}
+		}
+
+		addrs, _ := iface.Addrs()
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			ip, ok := netip.AddrFromSlice(ipnet.IP)
+			if !ok {
+				continue
+			}
+
+			ip = ip.Unmap()
+			prefixLen, _ := ipnet.Mask.Size()
+
+			family := "4"
+			if ip.Is6() {
+				family = "6"
+			}
+
+			info.Addresses = append(info.Addresses, interfaceAddress{
+				IP:        ip.String(),
+				PrefixLen: prefixLen,
+				Family:    family,
+				Scope:     addressScope(ip),
+			})
+		}
+
+		result = append(result, info)
+	}
+
+	return result
+}
+
+// addressScope returns the scope of the IP address.
+func addressScope(ip netip.Addr) string {
+	switch {
+	case ip.IsLoopback():
+		return scopeHost
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return scopeLinkLocal
+	case ip.IsPrivate():
+		return scopePrivate
+	default:
+		return scopeGlobal
+	}
+}
+
+// filterInterfaces returns the interfaces and addresses matching the filter.
+// The interfaces left without addresses by the family or scope filters are dropped.
+func filterInterfaces(ifaces []interfaceInfo, filter interfaceFilter) []interfaceInfo {
+	if len(filter.names) == 0 && filter.family == "" && filter.scope == "" {
+		return ifaces
+	}
+
+	result := []interfaceInfo{}
+
+	for _, iface := range ifaces {
+		if len(filter.names) > 0 && !containsString(filter.names, iface.Name) {
+			continue
+		}
+
+		if filter.family == "" && filter.scope == "" {
+			result = append(result, iface)
+
+			continue
+		}
+
+		addrs := []interfaceAddress{}
+
+		for _, addr := range iface.Addresses {
+			if (filter.family == "" || addr.Family == filter.family) && (filter.scope == "" || addr.Scope == filter.scope) {
+				addrs = append(addrs, addr)
+			}
+		}
+
+		if len(addrs) > 0 {
+			iface.Addresses = addrs
+			result = append(result, iface)
+		}
+	}
+
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package httpserver
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// kubernetesNamespaceFile is the namespace file of the mounted service account.
+const kubernetesNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Downward API environment variables of the pod name and namespace.
+const (
+	kubernetesPodNameEnv      = "POD_NAME"
+	kubernetesPodNamespaceEnv = "POD_NAMESPACE"
+)
+
+// kubernetesOptions holds the sources of the Kubernetes pod and node metadata.
+type kubernetesOptions struct {
+	// podInfoDir is the directory of the downward API "labels" and "annotations" files.
+	podInfoDir string
+	// nodeNameEnv, podIPEnv and zoneEnv are the environment variables set from the downward API.
+	nodeNameEnv string
+	podIPEnv    string
+	zoneEnv     string
+}
+
+type kubernetesInfo struct {
+	Namespace   string            `json:"namespace,omitempty"`
+	PodName     string            `json:"pod_name,omitempty"`
+	PodIP       string            `json:"pod_ip,omitempty"`
+	NodeName    string            `json:"node_name,omitempty"`
+	Zone        string            `json:"zone,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// getKubernetesInfo returns the pod and node metadata or nil if the server does not run in Kubernetes.
+//
+// The metadata is read from the downward API environment variables and files and the service account
+// namespace file. The server runs in Kubernetes if the KUBERNETES_SERVICE_HOST variable is set
+// or the service account namespace file exists.
+//
+// Parameters:
+// - env: The environment variables.
+// - hostname: The hostname used as the pod name if the POD_NAME variable is not set.
+// - opts: The metadata sources.
+//
+// Returns:
+// - *kubernetesInfo: The pod and node metadata.
+func getKubernetesInfo(env map[string]string, hostname string, opts *kubernetesOptions) *kubernetesInfo {
+	namespace, err := os.ReadFile(kubernetesNamespaceFile)
+	if err != nil && env["KUBERNETES_SERVICE_HOST"] == "" {
+		return nil
+	}
+
+	info := &kubernetesInfo{
+		Namespace: valueOr(env[kubernetesPodNamespaceEnv], strings.TrimSpace(string(namespace))),
+		PodName:   valueOr(env[kubernetesPodNameEnv], hostname),
+		PodIP:     env[opts.podIPEnv],
+		NodeName:  env[opts.nodeNameEnv],
+		Zone:      env[opts.zoneEnv],
+	}
+
+	if opts.podInfoDir != "" {
+		info.Labels = readDownwardAPIFile(filepath.Join(opts.podInfoDir, "labels"))
+		info.Annotations = readDownwardAPIFile(filepath.Join(opts.podInfoDir, "annotations"))
+	}
+
+	return info
+}
+
+// readDownwardAPIFile parses the key="value" lines of a downward API labels or annotations file.
+// It returns nil if the file cannot be read.
+func readDownwardAPIFile(name string) map[string]string {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+
+	result := make(map[string]string)
+
+	// The values are quoted so the annotations spanning multiple lines are escaped to a single line.
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		result[key] = value
+	}
+
+	return result
+}
@@ -0,0 +1,233 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+)
+
+// Listener names.
+const (
+	listenerHTTP  = "http"
+	listenerHTTPS = "https"
+	listenerAdmin = "admin"
+	listenerHTTP3 = "http3"
+	listenerGRPC  = "grpc"
+	listenerTCP   = "tcp-echo"
+	listenerUDP   = "udp-echo"
+)
+
+// listener is an HTTP server bound to a single address.
+// HTTP/3 listeners are served by the QUIC server over UDP,
+// gRPC listeners by the gRPC server and echo listeners by the echo server.
+type listener struct {
+	name   string
+	server *http.Server
+	quic   *http3.Server
+	grpc   *grpc.Server
+	echo   *echoServer
+	addr   string // Address of the gRPC listener.
+
+	// proxyProtocol enables the PROXY protocol on the TCP listener if set.
+	proxyProtocol *proxyProtocolOptions
+}
+
+// newListener creates a listener serving the handler on the given address.
+func newListener(name, addr string, h http.Handler, cfg *Config) *listener {
+	return &listener{
+		name: name,
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           h,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			ConnContext:       withConnInfo,
+		},
+	}
+}
+
+// newHTTP3Listener creates a QUIC listener serving the handler on the given UDP address.
+func newHTTP3Listener(addr string, h http.Handler) *listener {
+	return &listener{
+		name: listenerHTTP3,
+		quic: &http3.Server{
+			Addr:    addr,
+			Handler: h,
+			ConnContext: func(ctx context.Context, c quic.Connection) context.Context {
+				ctx = withConnInfo(ctx, nil)
+				getConnInfo(ctx).quicConn = c
+
+				return ctx
+			},
+		},
+	}
+}
+
+// newGRPCListener creates a listener serving the gRPC server on the given address.
+func newGRPCListener(addr string, srv *grpc.Server) *listener {
+	return &listener{
+		name: listenerGRPC,
+		grpc: srv,
+		addr: addr,
+	}
+}
+
+// newEchoListener creates a TCP or UDP echo listener on the given address.
+// The PROXY protocol is accepted by the TCP listener if proxyProtocol is set.
+func newEchoListener(network, addr string, proxyProtocol *proxyProtocolOptions, hostInfo *hostInfoProvider) *listener {
+	name := listenerTCP
+	if network == echoUDP {
+		name = listenerUDP
+	}
+
+	return &listener{
+		name: name,
+		echo: newEchoServer(network, addr, proxyProtocol, hostInfo),
+	}
+}
+
+// altSvcHandler wraps the handler to advertise the HTTP/3 listener on HTTP/1.1 and HTTP/2 responses.
+func altSvcHandler(h3 *http3.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			// The header is unavailable until the QUIC listener is started.
+			_ = h3.SetQUICHeaders(w.Header())
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve starts the listener and blocks until it is stopped.
+// The tlsConfig is used by the HTTPS, HTTP/3 and gRPC listeners only.
+func (l *listener) serve(tlsConfig *tls.Config) error {
+	if l.grpc != nil {
+		return l.serveGRPC(tlsConfig)
+	}
+
+	if l.echo != nil {
+		slog.Info(fmt.Sprintf("Starting %s server on address %s", l.name, l.echo.addr))
+
+		return l.echo.serve()
+	}
+
+	if l.quic != nil {
+		slog.Info(fmt.Sprintf("Starting %s server on UDP address %s", l.name, l.quic.Addr))
+
+		l.quic.TLSConfig = tlsConfig
+
+		if err := l.quic.ListenAndServe(); err != nil && err != http.ErrServerClosed && err != quic.ErrServerClosed {
+			return fmt.Errorf("quic.ListenAndServe: %w", err)
+		}
+
+		return nil
+	}
+
+	slog.Info(fmt.Sprintf("Starting %s server on address %s", l.name, l.server.Addr))
+
+	ln, err := listenTCP(l.server.Addr, l.proxyProtocol)
+	if err != nil {
+		return fmt.Errorf("listenTCP: %w", err)
+	}
+
+	if l.name == listenerHTTPS {
+		if supportsHTTP2(tlsConfig) {
+			l.server.TLSConfig = tlsConfig
+
+			if err := http2.ConfigureServer(l.server, newHTTP2Server()); err != nil {
+				return fmt.Errorf("http2.ConfigureServer: %w", err)
+			}
+		} else {
+			slog.Warn("HTTP/2 is disabled on the HTTPS listener, the TLS cipher suites include neither " +
+				"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 nor TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256")
+
+			// A non-nil TLSNextProto keeps http.Server from configuring HTTP/2 itself.
+			l.server.TLSConfig = withoutHTTP2(tlsConfig)
+			l.server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+
+		if err := l.server.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("server.ServeTLS: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := l.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("server.Serve: %w", err)
+	}
+
+	return nil
+}
+
+// serveGRPC starts the gRPC listener, over TLS if the tlsConfig is set, and blocks until it is stopped.
+func (l *listener) serveGRPC(tlsConfig *tls.Config) error {
+	slog.Info(fmt.Sprintf("Starting %s server on address %s", l.name, l.addr))
+
+	lis, err := listenTCP(l.addr, l.proxyProtocol)
+	if err != nil {
+		return fmt.Errorf("listenTCP: %w", err)
+	}
+
+	if tlsConfig != nil {
+		lis = tls.NewListener(lis, tlsConfig)
+	}
+
+	if err := l.grpc.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+		return fmt.Errorf("grpc.Serve: %w", err)
+	}
+
+	return nil
+}
+
+// shutdown gracefully shuts down the listener.
+// The gRPC listener streams still open at the context deadline are cancelled.
+func (l *listener) shutdown(ctx context.Context) error {
+	if l.echo != nil {
+		if err := l.echo.shutdown(ctx); err != nil {
+			return fmt.Errorf("echo.shutdown: %w", err)
+		}
+
+		return nil
+	}
+
+	if l.grpc != nil {
+		stopped := make(chan struct{})
+
+		go func() {
+			l.grpc.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			l.grpc.Stop()
+
+			return fmt.Errorf("grpc.GracefulStop: %w", ctx.Err())
+		}
+	}
+
+	if l.quic != nil {
+		if err := l.quic.Shutdown(ctx); err != nil {
+			return fmt.Errorf("quic.Shutdown: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := l.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("server.Shutdown: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package httpserver
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
+)
+
+// proxyProtocolHeaderTimeout is the time allowed to read the PROXY protocol header.
+// Connections without a header are served as is once it expires or data is received.
+const proxyProtocolHeaderTimeout = 5 * time.Second
+
+// proxyProtocolOptions holds the PROXY protocol settings of the TCP listeners.
+type proxyProtocolOptions struct {
+	// trustedCIDRs is the list of the networks allowed to send the header.
+	// Any source is allowed if the list is empty.
+	trustedCIDRs []netip.Prefix
+}
+
+type proxyProtocolInfo struct {
+	Version          int                `json:"version"`
+	Command          string             `json:"command"`
+	Transport        string             `json:"transport"`
+	SourceAddr       string             `json:"source_addr,omitempty"`
+	DestinationAddr  string             `json:"destination_addr,omitempty"`
+	PeerAddr         string             `json:"peer_addr"`
+	ALPN             string             `json:"alpn,omitempty"`
+	Authority        string             `json:"authority,omitempty"`
+	UniqueID         string             `json:"unique_id,omitempty"`
+	AWSVPCEndpointID string             `json:"aws_vpc_endpoint_id,omitempty"`
+	TLVs             []proxyProtocolTLV `json:"tlvs,omitempty"`
+}
+
+type proxyProtocolTLV struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+var proxyProtocolTransports = map[proxyproto.AddressFamilyAndProtocol]string{
+	proxyproto.TCPv4:        "tcp4",
+	proxyproto.TCPv6:        "tcp6",
+	proxyproto.UDPv4:        "udp4",
+	proxyproto.UDPv6:        "udp6",
+	proxyproto.UnixStream:   "unix-stream",
+	proxyproto.UnixDatagram: "unix-datagram",
+}
+
+// listenTCP listens on the TCP address. If opts is set, the PROXY protocol v1 and v2
+// headers of the connections are parsed and the connection remote address is replaced
+// by the source address of the header. The connections from untrusted sources
+// sending a header are rejected.
+func listenTCP(addr string, opts *proxyProtocolOptions) (net.Listener, error) {
+	var policy proxyproto.PolicyFunc
+
+	if opts != nil && len(opts.trustedCIDRs) > 0 {
+		cidrs := make([]string, 0, len(opts.trustedCIDRs))
+		for _, prefix := range opts.trustedCIDRs {
+			cidrs = append(cidrs, prefix.String())
+		}
+
+		var err error
+		if policy, err = proxyproto.StrictWhiteListPolicy(cidrs); err != nil {
+			return nil, fmt.Errorf("proxyproto.StrictWhiteListPolicy: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("net.Listen: %w", err)
+	}
+
+	if opts == nil {
+		return ln, nil
+	}
+
+	return &proxyproto.Listener{
+		Listener:          ln,
+		Policy:            policy,
+		ReadHeaderTimeout: proxyProtocolHeaderTimeout,
+	}, nil
+}
+
+// unwrapProxyProtocolConn returns the PROXY protocol connection underlying the connection or nil.
+func unwrapProxyProtocolConn(c net.Conn) *proxyproto.Conn {
+	for {
+		switch conn := c.(type) {
+		case *proxyproto.Conn:
+			return conn
+		case interface{ NetConn() net.Conn }: // *tls.Conn
+			c = conn.NetConn()
+		default:
+			return nil
+		}
+	}
+}
+
+// getProxyProtocolInfo returns the PROXY protocol header of the connection or nil if it has none.
+// The header is read on the first read from the connection so this must be called after it.
+func getProxyProtocolInfo(conn *proxyproto.Conn) *proxyProtocolInfo {
+	if conn == nil {
+		return nil
+	}
+
+	header := conn.ProxyHeader()
+	if header == nil {
+		return nil
+	}
+
+	info := &proxyProtocolInfo{
+		Version:   int(header.Version),
+		Command:   "PROXY",
+		Transport: "unspec",
+		PeerAddr:  conn.Raw().RemoteAddr().String(),
+	}
+
+	if header.Command.IsLocal() {
+		info.Command = "LOCAL"
+	}
+
+	if transport, ok := proxyProtocolTransports[header.TransportProtocol]; ok {
+		info.Transport = transport
+	}
+
+	if header.SourceAddr != nil {
+		info.SourceAddr = header.SourceAddr.String()
+	}
+
+	if header.DestinationAddr != nil {
+		info.DestinationAddr = header.DestinationAddr.String()
+	}
+
+	tlvs, err := header.TLVs()
+	if err != nil {
+		return info
+	}
+
+	for _, tlv := range tlvs {
+		switch {
+		case tlv.Type == proxyproto.PP2_TYPE_ALPN:
+			info.ALPN = string(tlv.Value)
+		case tlv.Type == proxyproto.PP2_TYPE_AUTHORITY:
+			info.Authority = string(tlv.Value)
+		case tlv.Type == proxyproto.PP2_TYPE_UNIQUE_ID:
+			info.UniqueID = hex.EncodeToString(tlv.Value)
+		case tlvparse.IsAWSVPCEndpointID(tlv):
+			info.AWSVPCEndpointID, _ = tlvparse.AWSVPCEndpointID(tlv)
+		}
+
+		info.TLVs = append(info.TLVs, proxyProtocolTLV{
+			Type:  fmt.Sprintf("0x%02x", byte(tlv.Type)),
+			Value: hex.EncodeToString(tlv.Value),
+		})
+	}
+
+	return info
+}
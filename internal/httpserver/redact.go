@@ -0,0 +1,94 @@
+package httpserver
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// redactedValue replaces the values of the secret environment variables and the sensitive headers.
+const redactedValue = "[REDACTED]"
+
+// filterEnvironment returns the environment variables selected by the allow and deny lists
+// of the configuration, with the values of the secret variables redacted.
+//
+// The lists hold case-insensitive glob patterns of the variable names. All the variables are allowed
+// if the allow list is empty, and the deny list takes precedence over the allow list.
+//
+// Parameters:
+// - env: The environment variables.
+// - cfg: The server configuration.
+//
+// Returns:
+// - map[string]string: The filtered environment variables.
+func filterEnvironment(env map[string]string, cfg *Config) map[string]string {
+	result := make(map[string]string, len(env))
+
+	for name, value := range env {
+		if len(cfg.EnvAllow) > 0 && !matchNamePattern(cfg.EnvAllow, name) {
+			continue
+		}
+
+		if matchNamePattern(cfg.EnvDeny, name) {
+			continue
+		}
+
+		if matchNamePattern(cfg.EnvRedact, name) {
+			value = redactedValue
+		}
+
+		result[name] = value
+	}
+
+	return result
+}
+
+// redactHeaders returns a copy of the headers with the values of the given header names redacted.
+func redactHeaders(h http.Header, names []string) http.Header {
+	result := h.Clone()
+
+	for name, values := range result {
+		if !isSensitiveHeader(names, name) {
+			continue
+		}
+
+		result[name] = redactValues(values)
+	}
+
+	return result
+}
+
+// redactValues returns a list of redacted values of the same length.
+func redactValues(values []string) []string {
+	redacted := make([]string, len(values))
+	for i := range redacted {
+		redacted[i] = redactedValue
+	}
+
+	return redacted
+}
+
+// isSensitiveHeader reports whether the header or metadata name is in the list, ignoring case.
+func isSensitiveHeader(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchNamePattern reports whether the name matches any of the glob patterns, ignoring case.
+// The patterns are validated by the configuration so the match errors are ignored.
+func matchNamePattern(patterns []string, name string) bool {
+	name = strings.ToUpper(name)
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,593 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Response formats.
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatXML  = "xml"
+	formatHTML = "html"
+	formatTOML = "toml"
+)
+
+// renderFunc writes the whoami data to w in a specific format.
+type renderFunc func(w io.Writer, data *jsonResponse) error
+
+type renderer struct {
+	contentType string
+	mediaTypes  []string
+	render      renderFunc
+}
+
+// renderers is the registry of response renderers keyed by format name.
+var renderers = map[string]*renderer{}
+
+func init() {
+	registerRenderer(formatText, "text/plain; charset=utf-8", []string{"text/plain"}, renderText)
+	registerRenderer(formatJSON, "application/json", []string{"application/json", "text/json"}, renderJSON)
+	registerRenderer(formatYAML, "application/yaml", []string{
+		"application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml",
+	}, renderYAML)
+	registerRenderer(formatXML, "application/xml; charset=utf-8", []string{"application/xml", "text/xml"}, renderXML)
+	registerRenderer(formatHTML, "text/html; charset=utf-8", []string{"text/html", "application/xhtml+xml"}, renderHTML)
+	registerRenderer(formatTOML, "application/toml", []string{"application/toml"}, renderTOML)
+}
+
+// registerRenderer adds a renderer for the given format to the registry.
+//
+// Parameters:
+// - format: The format name used by the "format" query parameter.
+// - contentType: The Content-Type header value of the rendered response.
+// - mediaTypes: The list of Accept header media types served by the renderer.
+// - fn: The function rendering the response body.
+func registerRenderer(format, contentType string, mediaTypes []string, fn renderFunc) {
+	renderers[format] = &renderer{
+		contentType: contentType,
+		mediaTypes:  mediaTypes,
+		render:      fn,
+	}
+}
+
+// negotiateFormat selects the response format for the request.
+//
+// The "format" query parameter takes precedence over the Accept header.
+// If neither of them matches a registered renderer the defaultFormat is used.
+// It returns an error if the "format" query parameter holds an unknown format.
+func negotiateFormat(r *http.Request, defaultFormat string) (string, error) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		if _, ok := renderers[format]; !ok {
+			return "", fmt.Errorf("unsupported format: %s", format)
+		}
+
+		return format, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return defaultFormat, nil
+	}
+
+	bestFormat := defaultFormat
+	bestQuality := 0.0
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if quality <= bestQuality {
+			continue
+		}
+
+		// Wildcards keep the route default format.
+		if mediaType == "*/*" {
+			bestFormat, bestQuality = defaultFormat, quality
+
+			continue
+		}
+
+		if format := formatByMediaType(mediaType); format != "" {
+			bestFormat, bestQuality = format, quality
+		}
+	}
+
+	return bestFormat, nil
+}
+
+// formatByMediaType returns the registered format serving the media type or an empty string.
+func formatByMediaType(mediaType string) string {
+	for format, r := range renderers {
+		for _, mt := range r.mediaTypes {
+			if mt == mediaType {
+				return format
+			}
+		}
+	}
+
+	return ""
+}
+
+// writeResponse renders the whoami data in the negotiated format and writes it to w.
+func writeResponse(w http.ResponseWriter, r *http.Request, data *jsonResponse, defaultFormat string) {
+	format, err := negotiateFormat(r, defaultFormat)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotAcceptable)
+
+		return
+	}
+
+	rnd := renderers[format]
+
+	var buf bytes.Buffer
+	if err := rnd.render(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", rnd.contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = buf.WriteTo(w)
+}
+
+func renderText(w io.Writer, data *jsonResponse) error {
+	_, err := fmt.Fprintf(w, `RequestID: %s
+Hostname: %s
+IP: %s
+Host: %s
+URL: %s
+Method: %s
+Proto: %s
+Params: %v
+Headers: %v
+UserAgent: %s
+RemoteAddr: %s
+Environment: %s
+
+`,
+		data.RequestID,
+		data.Hostname,
+		data.IP,
+		data.Host,
+		data.URL,
+		data.Method,
+		data.Proto,
+		data.Params,
+		data.Headers,
+		data.UserAgent,
+		data.RemoteAddr,
+		data.Environment,
+	)
+	if err != nil {
+		return fmt.Errorf("fmt.Fprintf: %w", err)
+	}
+
+	if p := data.Protocol; p != nil {
+		if _, err := fmt.Fprintf(w, "Protocol: HTTP/%d.%d H2C=%q ConnectionRequest=%d\n\n",
+			p.Major, p.Minor, p.H2C, p.ConnectionRequest,
+		); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+	}
+
+	if len(data.Interfaces) > 0 {
+		if _, err := io.WriteString(w, "Interfaces:\n"); err != nil {
+			return fmt.Errorf("io.WriteString: %w", err)
+		}
+
+		for _, iface := range data.Interfaces {
+			addrs := make([]string, 0, len(iface.Addresses))
+			for _, addr := range iface.Addresses {
+				addrs = append(addrs, fmt.Sprintf("%s/%d(%s)", addr.IP, addr.PrefixLen, addr.Scope))
+			}
+
+			if _, err := fmt.Fprintf(w, "  %s: MAC=%s MTU=%d Flags=%s Addresses=%s\n",
+				iface.Name, iface.MAC, iface.MTU, strings.Join(iface.Flags, ","), strings.Join(addrs, " "),
+			); err != nil {
+				return fmt.Errorf("fmt.Fprintf: %w", err)
+			}
+		}
+
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return fmt.Errorf("io.WriteString: %w", err)
+		}
+	}
+
+	if c := data.Client; c != nil {
+		hops := make([]string, 0, len(c.Hops))
+		for _, hop := range c.Hops {
+			hops = append(hops, fmt.Sprintf("%s(%s,trusted=%t)", hop.Addr, hop.Source, hop.Trusted))
+		}
+
+		if _, err := fmt.Fprintf(w, "Client: IP=%s Source=%s Proto=%s Host=%s Port=%s Hops=%s\n\n",
+			c.IP, c.Source, c.Proto, c.Host, c.Port, strings.Join(hops, " "),
+		); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+	}
+
+	if rt := data.Runtime; rt != nil {
+		if _, err := fmt.Fprintf(w, "Runtime: %s GOMAXPROCS=%d GOMEMLIMIT=%d NumCPU=%d StartTime=%s Uptime=%s ContainerID=%s\n",
+			rt.GoVersion, rt.GOMAXPROCS, rt.GOMEMLIMIT, rt.NumCPU, rt.StartTime.Format(time.RFC3339), rt.Uptime, rt.ContainerID,
+		); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+
+		if cg := rt.Cgroup; cg != nil {
+			if _, err := fmt.Fprintf(w, "Cgroup: v%d %s CPUQuota=%g MemoryLimit=%d MemoryUsage=%d\n",
+				cg.Version, cg.Path, cg.CPUQuota, cg.MemoryLimit, cg.MemoryUsage,
+			); err != nil {
+				return fmt.Errorf("fmt.Fprintf: %w", err)
+			}
+		}
+
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return fmt.Errorf("io.WriteString: %w", err)
+		}
+	}
+
+	if k := data.Kubernetes; k != nil {
+		if _, err := fmt.Fprintf(w, "Kubernetes: Namespace=%s Pod=%s PodIP=%s Node=%s Zone=%s Labels=%v\n\n",
+			k.Namespace, k.PodName, k.PodIP, k.NodeName, k.Zone, k.Labels,
+		); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+	}
+
+	if p := data.Proxy; p != nil {
+		if _, err := fmt.Fprintf(w, "ProxyProtocol: v%d %s %s Source=%s Destination=%s PeerAddr=%s ALPN=%q Authority=%q AWSVPCEndpointID=%q\n\n",
+			p.Version, p.Command, p.Transport, p.SourceAddr, p.DestinationAddr, p.PeerAddr, p.ALPN, p.Authority, p.AWSVPCEndpointID,
+		); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+	}
+
+	if data.TLS != nil {
+		if _, err := fmt.Fprintf(w, "TLS: %s %s ALPN=%q SNI=%q Resumed=%t\n",
+			data.TLS.Version, data.TLS.CipherSuite, data.TLS.NegotiatedProtocol, data.TLS.ServerName, data.TLS.DidResume,
+		); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+
+		if crt := data.TLS.ServerCertificate; crt != nil {
+			if _, err := fmt.Fprintf(w, "ServerCertificate: Name=%q Match=%s Subject=%q NotAfter=%s DNSNames=%v\n",
+				crt.Name, crt.Match, crt.Subject, crt.NotAfter.Format(time.RFC3339), crt.DNSNames,
+			); err != nil {
+				return fmt.Errorf("fmt.Fprintf: %w", err)
+			}
+		}
+
+		for _, crt := range data.TLS.ClientCertificates {
+			if _, err := fmt.Fprintf(w, "ClientCertificate: Subject=%q Issuer=%q Serial=%s NotAfter=%s SANs=%v\n",
+				crt.Subject, crt.Issuer, crt.SerialNumber, crt.NotAfter.Format(time.RFC3339),
+				crt.subjectAltNames(),
+			); err != nil {
+				return fmt.Errorf("fmt.Fprintf: %w", err)
+			}
+		}
+
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return fmt.Errorf("io.WriteString: %w", err)
+		}
+	}
+
+	if data.Body != nil {
+		if _, err := fmt.Fprintf(w, "Body (%s, %d bytes):\n%s\n\n", data.Body.Encoding, data.Body.Size, data.Body.Raw); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func renderJSON(w io.Writer, data *jsonResponse) error {
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		return fmt.Errorf("json.Encode: %w", err)
+	}
+
+	return nil
+}
+
+func renderYAML(w io.Writer, data *jsonResponse) error {
+	tree, err := toGeneric(data)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(tree); err != nil {
+		return fmt.Errorf("yaml.Encode: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("yaml.Close: %w", err)
+	}
+
+	return nil
+}
+
+func renderTOML(w io.Writer, data *jsonResponse) error {
+	tree, err := toGeneric(data)
+	if err != nil {
+		return err
+	}
+
+	if err := toml.NewEncoder(w).Encode(dropNulls(tree)); err != nil {
+		return fmt.Errorf("toml.Encode: %w", err)
+	}
+
+	return nil
+}
+
+func renderXML(w io.Writer, data *jsonResponse) error {
+	tree, err := toGeneric(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("io.WriteString: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := encodeXMLElement(enc, "whoami", tree); err != nil {
+		return err
+	}
+
+	if err := enc.Flush(); err != nil {
+		return fmt.Errorf("xml.Flush: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("io.WriteString: %w", err)
+	}
+
+	return nil
+}
+
+// encodeXMLElement writes the generic value as an XML element with the given name.
+// Map keys which are not valid XML names are written as <entry key="..."> elements
+// and slice items as repeated <item> elements.
+func encodeXMLElement(enc *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "entry"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+		}
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return fmt.Errorf("xml.EncodeToken: %w", err)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			if err := encodeXMLElement(enc, k, v[k]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := encodeXMLElement(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return fmt.Errorf("xml.EncodeToken: %w", err)
+		}
+	}
+
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return fmt.Errorf("xml.EncodeToken: %w", err)
+	}
+
+	return nil
+}
+
+// isXMLName reports whether s can be used as an XML element name as is.
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c == '-' || c == '.' || c >= '0' && c <= '9'):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+var htmlTemplate = template.Must(template.New("whoami").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>whoami</title>
+<style>
+body { font-family: monospace; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>whoami</h1>
+{{template "value" .}}
+</body>
+</html>
+{{define "value"}}{{if .Map}}<table>
+{{range .Map}}<tr><th>{{.Key}}</th><td>{{template "value" .Value}}</td></tr>
+{{end}}</table>{{else if .List}}<ul>
+{{range .List}}<li>{{template "value" .}}</li>
+{{end}}</ul>{{else}}{{.Scalar}}{{end}}{{end}}
+`))
+
+// htmlNode is a generic value prepared for the HTML template.
+type htmlNode struct {
+	Map    []htmlEntry
+	List   []htmlNode
+	Scalar string
+}
+
+type htmlEntry struct {
+	Key   string
+	Value htmlNode
+}
+
+func renderHTML(w io.Writer, data *jsonResponse) error {
+	tree, err := toGeneric(data)
+	if err != nil {
+		return err
+	}
+
+	if err := htmlTemplate.Execute(w, newHTMLNode(tree)); err != nil {
+		return fmt.Errorf("template.Execute: %w", err)
+	}
+
+	return nil
+}
+
+func newHTMLNode(value any) htmlNode {
+	switch v := value.(type) {
+	case map[string]any:
+		node := htmlNode{Map: make([]htmlEntry, 0, len(v))}
+		for _, k := range sortedKeys(v) {
+			node.Map = append(node.Map, htmlEntry{Key: k, Value: newHTMLNode(v[k])})
+		}
+
+		return node
+	case []any:
+		node := htmlNode{List: make([]htmlNode, 0, len(v))}
+		for _, item := range v {
+			node.List = append(node.List, newHTMLNode(item))
+		}
+
+		return node
+	case nil:
+		return htmlNode{}
+	default:
+		return htmlNode{Scalar: fmt.Sprint(v)}
+	}
+}
+
+// toGeneric converts the whoami data into a tree of maps, slices and scalars
+// keyed by the JSON field names, so that every format shares the same field names.
+func toGeneric(data *jsonResponse) (map[string]any, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var tree map[string]any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("json.Decode: %w", err)
+	}
+
+	normalizeNumbers(tree)
+
+	return tree, nil
+}
+
+// normalizeNumbers replaces json.Number values in the generic tree with int64 or float64 values.
+func normalizeNumbers(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = normalizeNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+
+	return value
+}
+
+// dropNulls removes null values from the generic tree as they cannot be represented in TOML.
+func dropNulls(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if item == nil {
+				delete(v, k)
+
+				continue
+			}
+
+			v[k] = dropNulls(item)
+		}
+	case []any:
+		items := v[:0]
+		for _, item := range v {
+			if item != nil {
+				items = append(items, dropNulls(item))
+			}
+		}
+
+		return items
+	}
+
+	return value
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,88 @@
+package httpserver
+
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/andymarkow/whoami/internal/container"
+)
+
+// processStartTime is the time the process started.
+var processStartTime = getProcessStartTime()
+
+// getProcessStartTime returns the start time of the process, or the package initialization time
+// if it cannot be read. The boot time has a second precision, so the start time is capped
+// to the initialization time.
+func getProcessStartTime() time.Time {
+	now := time.Now()
+
+	start, err := readProcessStartTime()
+	if err != nil || start.After(now) {
+		return now
+	}
+
+	return start
+}
+
+type runtimeInfo struct {
+	GoVersion   string      `json:"go_version"`
+	GOMAXPROCS  int         `json:"gomaxprocs"`
+	GOMEMLIMIT  int64       `json:"gomemlimit,omitempty"`
+	NumCPU      int         `json:"num_cpu"`
+	StartTime   time.Time   `json:"start_time"`
+	Uptime      string      `json:"uptime"`
+	ContainerID string      `json:"container_id,omitempty"`
+	Cgroup      *cgroupInfo `json:"cgroup,omitempty"`
+}
+
+type cgroupInfo struct {
+	Version     int     `json:"version"`
+	Path        string  `json:"path"`
+	CPUQuota    float64 `json:"cpu_quota,omitempty"`
+	MemoryLimit int64   `json:"memory_limit,omitempty"`
+	MemoryUsage int64   `json:"memory_usage,omitempty"`
+}
+
+// getRuntimeInfo returns the Go runtime settings, the process uptime and the container resources.
+// The container ID and the cgroup limits are read from the snapshot, the memory usage is read on each call.
+//
+// Parameters:
+// - c: The container details of the snapshot.
+//
+// Returns:
+// - *runtimeInfo: The runtime details.
+func getRuntimeInfo(c *container.Info) *runtimeInfo {
+	info := &runtimeInfo{
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		StartTime:  processStartTime.UTC(),
+		Uptime:     time.Since(processStartTime).Round(time.Second).String(),
+	}
+
+	// A negative input returns the limit without changing it, math.MaxInt64 means no limit.
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		info.GOMEMLIMIT = limit
+	}
+
+	if c == nil {
+		return info
+	}
+
+	info.ContainerID = c.ID
+
+	if cg := c.Cgroup; cg != nil {
+		info.Cgroup = &cgroupInfo{
+			Version:     cg.Version,
+			Path:        cg.Path,
+			CPUQuota:    cg.CPUQuota,
+			MemoryLimit: cg.MemoryLimit,
+		}
+
+		info.Cgroup.MemoryUsage, _ = cg.MemoryUsage()
+	}
+
+	return info
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package httpserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userHZ is the unit of the clock ticks reported by the /proc files, fixed to 100 on the common architectures.
+const userHZ = 100
+
+// readProcessStartTime returns the start time of the process from the /proc/self/stat
+// start time in clock ticks since the boot and the /proc/stat boot time.
+func readProcessStartTime() (time.Time, error) {
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	// The command name may hold spaces and parentheses, the fields are counted from its closing parenthesis.
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return time.Time{}, errors.New("invalid /proc/self/stat format")
+	}
+
+	// The start time is the 22nd field, the fields after the command name start with the 3rd one.
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 20 {
+		return time.Time{}, errors.New("invalid /proc/self/stat format")
+	}
+
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("strconv.ParseInt: %w", err)
+	}
+
+	procStat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	for _, line := range strings.Split(string(procStat), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			bootTime, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("strconv.ParseInt: %w", err)
+			}
+
+			return time.Unix(bootTime, 0).Add(time.Duration(ticks) * time.Second / userHZ), nil
+		}
+	}
+
+	return time.Time{}, errors.New("boot time not found in /proc/stat")
+}
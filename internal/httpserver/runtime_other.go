@@ -0,0 +1,13 @@
+//go:build !linux
+
+package httpserver
+
+import (
+	"errors"
+	"time"
+)
+
+// readProcessStartTime is not supported on this platform, the package initialization time is used.
+func readProcessStartTime() (time.Time, error) {
+	return time.Time{}, errors.New("process start time is not supported on this platform")
+}
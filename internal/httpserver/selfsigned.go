@@ -0,0 +1,158 @@
+package httpserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// selfSignedValidity is the validity period of the generated certificates.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// getSelfSignedSANs returns the default subject alternative names of the
+// self-signed certificate: the hostname, localhost and the local IP addresses.
+func getSelfSignedSANs() []string {
+	sans := []string{"localhost", "127.0.0.1"}
+
+	if hostname, err := os.Hostname(); err == nil {
+		sans = append(sans, hostname)
+	}
+
+	return append(sans, getLocalIPs(getInterfaces())...)
+}
+
+// setSelfSignedCertificate generates the self-signed certificate served by the cert manager
+// and writes the CA certificate to the caFile if it is set.
+func setSelfSignedCertificate(certManager *certManager, sans []string, caFile string) error {
+	if len(sans) == 0 {
+		sans = getSelfSignedSANs()
+	}
+
+	cert, caPEM, err := generateSelfSigned(sans)
+	if err != nil {
+		return fmt.Errorf("generateSelfSigned: %w", err)
+	}
+
+	certManager.setCertificate(cert)
+
+	slog.Info(fmt.Sprintf("Generated self-signed TLS certificate for %s", strings.Join(sans, ", ")))
+
+	if caFile != "" {
+		if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+			return fmt.Errorf("os.WriteFile: %w", err)
+		}
+
+		slog.Info(fmt.Sprintf("Self-signed CA certificate written to %s", caFile))
+	}
+
+	return nil
+}
+
+// generateSelfSigned generates an in-memory CA and a leaf certificate signed by it.
+//
+// Parameters:
+// - sans: The list of DNS names and IP addresses of the leaf certificate.
+//
+// Returns:
+// - *tls.Certificate: The leaf certificate with its private key.
+// - []byte: The PEM encoded CA certificate.
+// - error: An error if the certificates could not be generated.
+func generateSelfSigned(sans []string) (*tls.Certificate, []byte, error) {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := notBefore.Add(selfSignedValidity)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ecdsa.GenerateKey: %w", err)
+	}
+
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{Organization: []string{"whoami"}, CommonName: "whoami self-signed CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	if caTemplate.SerialNumber, err = randomSerialNumber(); err != nil {
+		return nil, nil, err
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("x509.CreateCertificate: %w", err)
+	}
+
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, nil, fmt.Errorf("x509.ParseCertificate: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ecdsa.GenerateKey: %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{Organization: []string{"whoami"}},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	if template.SerialNumber, err = randomSerialNumber(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, san)
+		}
+	}
+
+	if len(template.DNSNames) > 0 {
+		template.Subject.CommonName = template.DNSNames[0]
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("x509.CreateCertificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("x509.ParseCertificate: %w", err)
+	}
+
+	cert := &tls.Certificate{
+		Certificate: [][]byte{der, caDER},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), nil
+}
+
+func randomSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("rand.Int: %w", err)
+	}
+
+	return serial, nil
+}
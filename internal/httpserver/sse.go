@@ -0,0 +1,139 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sseDefaultInterval is the default interval of the server-sent events.
+const sseDefaultInterval = time.Second
+
+// sseOptions holds the event stream options set by the query parameters.
+type sseOptions struct {
+	interval time.Duration // Interval of the events.
+	count    int64         // Last event ID of the stream, zero means unlimited.
+	retry    time.Duration // Reconnection time hint sent to the client, zero disables it.
+	lastID   int64         // Last event ID received by the client.
+}
+
+type sseEvent struct {
+	ID        int64     `json:"id"`
+	Hostname  string    `json:"hostname"`
+	RequestID string    `json:"request_id"`
+	Time      time.Time `json:"time"`
+}
+
+// parseSSEOptions parses the event stream options from the query parameters
+// and the Last-Event-ID header of a reconnecting client.
+func parseSSEOptions(r *http.Request) (*sseOptions, error) {
+	query := r.URL.Query()
+	opts := &sseOptions{interval: sseDefaultInterval}
+
+	var err error
+
+	if query.Has("interval") {
+		if opts.interval, err = time.ParseDuration(query.Get("interval")); err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+
+		if opts.interval <= 0 {
+			return nil, fmt.Errorf("invalid interval: %s", query.Get("interval"))
+		}
+	}
+
+	if query.Has("count") {
+		if opts.count, err = strconv.ParseInt(query.Get("count"), 10, 64); err != nil || opts.count < 0 {
+			return nil, fmt.Errorf("invalid count: %s", query.Get("count"))
+		}
+	}
+
+	if query.Has("retry") {
+		if opts.retry, err = time.ParseDuration(query.Get("retry")); err != nil {
+			return nil, fmt.Errorf("invalid retry: %w", err)
+		}
+	}
+
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		if opts.lastID, err = strconv.ParseInt(lastID, 10, 64); err != nil || opts.lastID < 0 {
+			return nil, fmt.Errorf("invalid Last-Event-ID: %s", lastID)
+		}
+	}
+
+	return opts, nil
+}
+
+// sseHandler streams server-sent events with the hostname and request ID.
+//
+// The events are numbered from 1 and sent every interval until the count event is sent
+// or the client disconnects. A client reconnecting with the Last-Event-ID header resumes
+// the stream from the next event. The stream that is already complete is answered
+// with 204 No Content which tells the client to stop reconnecting.
+// The streams end when the stop channel is closed so they do not hold the server shutdown.
+func sseHandler(hostInfo *hostInfoProvider, stop <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseSSEOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if opts.count > 0 && opts.lastID >= opts.count {
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		rc := http.NewResponseController(w)
+
+		// The stream outlives the server write timeout.
+		_ = getResponseController(r, w).SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+
+		if opts.retry > 0 {
+			fmt.Fprintf(w, "retry: %d\n\n", opts.retry.Milliseconds())
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+
+		requestID := r.Header.Get("X-Request-ID")
+
+		for id := opts.lastID + 1; opts.count == 0 || id <= opts.count; id++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-stop:
+				return
+			case t := <-ticker.C:
+				payload, err := json.Marshal(sseEvent{
+					ID:        id,
+					Hostname:  hostInfo.get().hostname,
+					RequestID: requestID,
+					Time:      t.UTC(),
+				})
+				if err != nil {
+					return
+				}
+
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: whoami\ndata: %s\n\n", id, payload); err != nil {
+					return
+				}
+
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
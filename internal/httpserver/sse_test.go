@@ -0,0 +1,59 @@
+package httpserver_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andymarkow/whoami/internal/httpserver"
+)
+
+func TestSSEShutdown(t *testing.T) {
+	addr, shutdown := startServer(t, &httpserver.Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/sse?interval=50ms", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequestWithContext: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("http.DefaultClient.Do: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Wait for the first event so the stream is open when the shutdown begins.
+	body := bufio.NewReader(resp.Body)
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			t.Fatalf("body.ReadString: %v", err)
+		}
+
+		if strings.HasPrefix(line, "id: ") {
+			break
+		}
+	}
+
+	start := time.Now()
+
+	if err := shutdown(); err != nil {
+		t.Fatalf("srv.Shutdown: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("shutdown took %s with an open stream", elapsed)
+	}
+
+	// The stream ends instead of being cut by the shutdown timeout.
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		t.Errorf("stream read: %v", err)
+	}
+}
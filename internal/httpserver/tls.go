@@ -0,0 +1,206 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// TLS client authentication modes.
+const (
+	ClientAuthNone             = "none"
+	ClientAuthRequest          = "request"
+	ClientAuthRequire          = "require"
+	ClientAuthVerifyIfGiven    = "verify-if-given"
+	ClientAuthRequireAndVerify = "require-and-verify"
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	ClientAuthNone:             tls.NoClientCert,
+	ClientAuthRequest:          tls.RequestClientCert,
+	ClientAuthRequire:          tls.RequireAnyClientCert,
+	ClientAuthVerifyIfGiven:    tls.VerifyClientCertIfGiven,
+	ClientAuthRequireAndVerify: tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsCurves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+}
+
+// tlsOptions holds the TLS certificates and handshake settings of the server.
+type tlsOptions struct {
+	certFile         string
+	keyFile          string
+	certPairs        []string
+	certDir          string
+	caFile           string
+	clientAuth       string
+	minVersion       string
+	maxVersion       string
+	cipherSuites     []string
+	curves           []string
+	reloadInterval   time.Duration
+	selfSigned       bool
+	selfSignedSANs   []string
+	selfSignedCAFile string
+}
+
+// newServerTLSConfig returns the tls.Config of the HTTPS listeners.
+//
+// The certificate is selected by the SNI server name among the configured certificates.
+// The default one is the self-signed certificate if enabled, then the tls-cert
+// certificate, then the first one of the list and of the directory.
+// The certificate, key and CA files are reloaded on change with the configured
+// interval until the stop channel is closed.
+func newServerTLSConfig(opts *tlsOptions, stop <-chan struct{}) (*tls.Config, error) {
+	tlsConfig, err := getTLSConfig(opts)
+	if err != nil {
+		return nil, fmt.Errorf("getTLSConfig: %w", err)
+	}
+
+	pairs, err := parseCertKeyPairs(opts.certPairs)
+	if err != nil {
+		return nil, fmt.Errorf("parseCertKeyPairs: %w", err)
+	}
+
+	if opts.certFile != "" || opts.keyFile != "" {
+		pairs = append([]certKeyPair{{certFile: opts.certFile, keyFile: opts.keyFile}}, pairs...)
+	}
+
+	certManager, err := newCertManager(pairs, opts.certDir, opts.caFile, opts.reloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("newCertManager: %w", err)
+	}
+
+	if opts.selfSigned {
+		if err := setSelfSignedCertificate(certManager, opts.selfSignedSANs, opts.selfSignedCAFile); err != nil {
+			return nil, fmt.Errorf("setSelfSignedCertificate: %w", err)
+		}
+	}
+
+	if certs := certManager.certs.Load(); certs == nil || len(*certs) == 0 {
+		return nil, errors.New("no TLS certificate configured")
+	}
+
+	go certManager.Run(stop)
+
+	return certManager.tlsConfig(tlsConfig), nil
+}
+
+// getTLSConfig returns a tls.Config and an error. It reads the CA certificate
+// from the specified file and creates a tls.Config object with the client
+// authentication type, client certificate authority, TLS versions, cipher
+// suites and curves. This is used to configure the server's mutual TLS configuration.
+//
+// The client authentication mode defaults to require-and-verify if the CA
+// certificate file is set and to none otherwise.
+func getTLSConfig(opts *tlsOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	clientAuth := opts.clientAuth
+	if clientAuth == "" {
+		clientAuth = ClientAuthNone
+		if opts.caFile != "" {
+			clientAuth = ClientAuthRequireAndVerify
+		}
+	}
+
+	var ok bool
+	if cfg.ClientAuth, ok = clientAuthTypes[strings.ToLower(clientAuth)]; !ok {
+		return nil, fmt.Errorf("unknown TLS client auth mode: %s", clientAuth)
+	}
+
+	if opts.caFile != "" {
+		var err error
+		if cfg.ClientCAs, err = loadCertPool(opts.caFile); err != nil {
+			return nil, fmt.Errorf("loadCertPool: %w", err)
+		}
+	} else if cfg.ClientAuth == tls.VerifyClientCertIfGiven || cfg.ClientAuth == tls.RequireAndVerifyClientCert {
+		return nil, fmt.Errorf("TLS client auth mode %s requires a CA certificate file", clientAuth)
+	}
+
+	if opts.minVersion != "" {
+		if cfg.MinVersion, ok = tlsVersions[opts.minVersion]; !ok {
+			return nil, fmt.Errorf("unknown TLS min version: %s", opts.minVersion)
+		}
+	}
+
+	if opts.maxVersion != "" {
+		if cfg.MaxVersion, ok = tlsVersions[opts.maxVersion]; !ok {
+			return nil, fmt.Errorf("unknown TLS max version: %s", opts.maxVersion)
+		}
+
+		if cfg.MaxVersion < cfg.MinVersion {
+			return nil, fmt.Errorf("TLS max version %s is lower than min version", opts.maxVersion)
+		}
+	}
+
+	for _, name := range opts.cipherSuites {
+		id, err := cipherSuiteID(name)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.CipherSuites = append(cfg.CipherSuites, id)
+	}
+
+	for _, name := range opts.curves {
+		curve, ok := tlsCurves[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS curve: %s", name)
+		}
+
+		cfg.CurvePreferences = append(cfg.CurvePreferences, curve)
+	}
+
+	return cfg, nil
+}
+
+// loadCertPool reads the PEM encoded certificates from the file into a new certificate pool.
+// It returns an error if the file does not contain any valid certificate.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid PEM certificates found in %s", file)
+	}
+
+	return pool, nil
+}
+
+// cipherSuiteID returns the ID of the cipher suite with the given IANA name.
+func cipherSuiteID(name string) (uint16, error) {
+	for _, cs := range tls.CipherSuites() {
+		if cs.Name == name {
+			return cs.ID, nil
+		}
+	}
+
+	for _, cs := range tls.InsecureCipherSuites() {
+		if cs.Name == name {
+			return cs.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown TLS cipher suite: %s", name)
+}
@@ -0,0 +1,99 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type tlsInfo struct {
+	Version            string                 `json:"version"`
+	CipherSuite        string                 `json:"cipher_suite"`
+	NegotiatedProtocol string                 `json:"negotiated_protocol,omitempty"`
+	ServerName         string                 `json:"server_name,omitempty"`
+	DidResume          bool                   `json:"did_resume"`
+	ServerCertificate  *servedCertificateInfo `json:"server_certificate,omitempty"`
+	ClientCertificates []certificateInfo      `json:"client_certificates,omitempty"`
+}
+
+type certificateInfo struct {
+	Subject        string    `json:"subject"`
+	Issuer         string    `json:"issuer"`
+	SerialNumber   string    `json:"serial_number"`
+	NotBefore      time.Time `json:"not_before"`
+	NotAfter       time.Time `json:"not_after"`
+	DNSNames       []string  `json:"dns_names,omitempty"`
+	IPAddresses    []string  `json:"ip_addresses,omitempty"`
+	EmailAddresses []string  `json:"email_addresses,omitempty"`
+	URIs           []string  `json:"uris,omitempty"`
+	SPIFFEID       string    `json:"spiffe_id,omitempty"`
+}
+
+// getTLSInfo returns the TLS connection details of the request or nil for plain connections.
+//
+// The verified client certificate chain is reported when available, otherwise
+// the certificates presented by the client are reported as is.
+func getTLSInfo(r *http.Request) *tlsInfo {
+	state := r.TLS
+	if state == nil {
+		return nil
+	}
+
+	info := &tlsInfo{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		ServerName:         state.ServerName,
+		DidResume:          state.DidResume,
+		ServerCertificate:  getServedCertificate(r.Context()),
+	}
+
+	chain := state.PeerCertificates
+	if len(state.VerifiedChains) > 0 {
+		chain = state.VerifiedChains[0]
+	}
+
+	for _, crt := range chain {
+		info.ClientCertificates = append(info.ClientCertificates, newCertificateInfo(crt))
+	}
+
+	return info
+}
+
+func newCertificateInfo(crt *x509.Certificate) certificateInfo {
+	info := certificateInfo{
+		Subject:        crt.Subject.String(),
+		Issuer:         crt.Issuer.String(),
+		SerialNumber:   fmt.Sprintf("%X", crt.SerialNumber),
+		NotBefore:      crt.NotBefore.UTC(),
+		NotAfter:       crt.NotAfter.UTC(),
+		DNSNames:       crt.DNSNames,
+		EmailAddresses: crt.EmailAddresses,
+	}
+
+	for _, ip := range crt.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+
+	for _, uri := range crt.URIs {
+		info.URIs = append(info.URIs, uri.String())
+
+		if uri.Scheme == "spiffe" && info.SPIFFEID == "" {
+			info.SPIFFEID = uri.String()
+		}
+	}
+
+	return info
+}
+
+// subjectAltNames returns all subject alternative names of the certificate.
+func (c certificateInfo) subjectAltNames() []string {
+	sans := make([]string, 0, len(c.DNSNames)+len(c.IPAddresses)+len(c.EmailAddresses)+len(c.URIs))
+	sans = append(sans, c.DNSNames...)
+	sans = append(sans, c.IPAddresses...)
+	sans = append(sans, c.EmailAddresses...)
+
+	return append(sans, c.URIs...)
+}
@@ -0,0 +1,307 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsWriteWait is the time allowed to write a WebSocket frame to the client.
+const wsWriteWait = 10 * time.Second
+
+var wsUpgrader = websocket.Upgrader{
+	// Any origin is accepted as whoami is meant to be reached through arbitrary proxies.
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// wsOptions holds the WebSocket session options set by the query parameters.
+type wsOptions struct {
+	interval    time.Duration // Interval of the server pushed messages, zero disables them.
+	count       int           // Number of the server pushed messages, zero means unlimited.
+	ping        time.Duration // Interval of the server pings, zero disables them.
+	closeCode   int           // Close code sent by the server, zero disables the server close.
+	closeAfter  time.Duration // Delay of the server close after the upgrade.
+	closeReason string
+}
+
+type wsPushMessage struct {
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+}
+
+// parseWSOptions parses the WebSocket session options from the query parameters.
+//
+// Parameters:
+// - r: The upgrade request with the interval, count, ping, close, close_after and close_reason query parameters.
+//
+// Returns:
+// - *wsOptions: The session options.
+// - error: An error if any of the query parameters is invalid.
+func parseWSOptions(r *http.Request) (*wsOptions, error) {
+	query := r.URL.Query()
+	opts := &wsOptions{closeReason: query.Get("close_reason")}
+
+	for name, d := range map[string]*time.Duration{
+		"interval":    &opts.interval,
+		"ping":        &opts.ping,
+		"close_after": &opts.closeAfter,
+	} {
+		if !query.Has(name) {
+			continue
+		}
+
+		var err error
+		if *d, err = time.ParseDuration(query.Get(name)); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+
+		if *d < 0 {
+			return nil, fmt.Errorf("invalid %s: negative duration", name)
+		}
+	}
+
+	if query.Has("count") {
+		var err error
+		if opts.count, err = strconv.Atoi(query.Get("count")); err != nil || opts.count < 0 {
+			return nil, fmt.Errorf("invalid count: %s", query.Get("count"))
+		}
+	}
+
+	if query.Has("close") {
+		var err error
+		if opts.closeCode, err = strconv.Atoi(query.Get("close")); err != nil || !isValidCloseCode(opts.closeCode) {
+			return nil, fmt.Errorf("invalid close code: %s", query.Get("close"))
+		}
+	}
+
+	return opts, nil
+}
+
+// isValidCloseCode reports whether the code may be sent in a close frame.
+func isValidCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1014:
+		return false
+	}
+
+	// Reserved codes which must not be set in a close frame.
+	return code != 1004 && code != 1005 && code != 1006
+}
+
+// wsHandler upgrades the request to a WebSocket connection.
+//
+// The first message holds the whoami data rendered in the format selected by
+// the "format" query parameter, JSON by default. The text and binary messages
+// of the client are echoed back and its pings are answered with pongs.
+// The server can push periodic messages, send pings and close the connection
+// with a given code as set by the query parameters.
+func wsHandler(cfg *Config, hostInfo *hostInfoProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseWSOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		format := formatJSON
+		if r.URL.Query().Has("format") {
+			if format, err = negotiateFormat(r, formatJSON); err != nil {
+				http.Error(w, err.Error(), http.StatusNotAcceptable)
+
+				return
+			}
+		}
+
+		data, err := getWhoamiData(r, cfg, hostInfo.get())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := renderers[format].render(&buf, data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		// The upgrader replies with an HTTP error itself if the handshake fails.
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Report the handshake status to the access log and metrics middlewares.
+		w.WriteHeader(http.StatusSwitchingProtocols)
+
+		// The connection outlives the server read and write timeouts.
+		_ = conn.NetConn().SetDeadline(time.Time{})
+
+		if err := serveWS(conn, buf.Bytes(), opts); err != nil {
+			slog.Debug(fmt.Sprintf("WebSocket session closed: %v", err))
+		}
+	})
+}
+
+// serveWS runs the WebSocket session until the connection is closed.
+func serveWS(conn *websocket.Conn, initial []byte, opts *wsOptions) error {
+	write := func(messageType int, data []byte) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+
+		return conn.WriteMessage(messageType, data)
+	}
+
+	if err := write(websocket.TextMessage, initial); err != nil {
+		return fmt.Errorf("conn.WriteMessage: %w", err)
+	}
+
+	type message struct {
+		messageType int
+		data        []byte
+	}
+
+	// All the writes but control frames happen in this goroutine, the reader only forwards the messages to echo.
+	// The reader stops forwarding when the loop returns, as the connection is closed by the caller afterwards.
+	messages := make(chan message)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				close(messages)
+
+				return
+			}
+
+			select {
+			case messages <- message{messageType: messageType, data: data}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var pushC, pingC, closeC <-chan time.Time
+
+	if opts.interval > 0 {
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		pushC = ticker.C
+	}
+
+	if opts.ping > 0 {
+		ticker := time.NewTicker(opts.ping)
+		defer ticker.Stop()
+		pingC = ticker.C
+	}
+
+	if opts.closeCode != 0 {
+		timer := time.NewTimer(opts.closeAfter)
+		defer timer.Stop()
+		closeC = timer.C
+	}
+
+	pushed := 0
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return <-readErr
+			}
+
+			if err := write(msg.messageType, msg.data); err != nil {
+				return fmt.Errorf("conn.WriteMessage: %w", err)
+			}
+		case t := <-pushC:
+			pushed++
+
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(wsPushMessage{Seq: pushed, Time: t.UTC()}); err != nil {
+				return fmt.Errorf("conn.WriteJSON: %w", err)
+			}
+
+			if opts.count > 0 && pushed >= opts.count {
+				pushC = nil
+			}
+		case <-pingC:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return fmt.Errorf("conn.WriteControl: %w", err)
+			}
+		case <-closeC:
+			frame := websocket.FormatCloseMessage(opts.closeCode, opts.closeReason)
+			if err := conn.WriteControl(websocket.CloseMessage, frame, time.Now().Add(wsWriteWait)); err != nil {
+				return fmt.Errorf("conn.WriteControl: %w", err)
+			}
+
+			// Wait for the client to answer the close frame before closing the connection.
+			closeC = nil
+			_ = conn.SetReadDeadline(time.Now().Add(wsWriteWait))
+		}
+	}
+}
+
+// hijackGuard wraps the handler to drop the WriteHeader calls made after the
+// connection is hijacked. It lets the handlers report the status of upgraded
+// connections to the access log and metrics middlewares.
+func hijackGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&hijackGuardWriter{ResponseWriter: w}, r)
+	})
+}
+
+type hijackGuardWriter struct {
+	http.ResponseWriter
+	hijacked atomic.Bool
+}
+
+func (w *hijackGuardWriter) WriteHeader(statusCode int) {
+	if w.hijacked.Load() {
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *hijackGuardWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *hijackGuardWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.ResponseWriter is not an http.Hijacker")
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.hijacked.Store(true)
+	}
+
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter for http.ResponseController.
+func (w *hijackGuardWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,8 +1,12 @@
 package telemetry
 
 import (
+	"math"
 	"runtime"
+	"runtime/debug"
+	"strconv"
 
+	"github.com/andymarkow/whoami/internal/container"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -21,17 +25,45 @@ func Init(version string) {
 			},
 		})
 
+	runtimeLabels := map[string]string{
+		"go_version":     runtime.Version(),
+		"os":             runtime.GOOS,
+		"arch":           runtime.GOARCH,
+		"gomaxprocs":     strconv.Itoa(runtime.GOMAXPROCS(0)),
+		"gomemlimit":     "",
+		"container_id":   "",
+		"cgroup_version": "",
+		"cpu_quota":      "",
+		"memory_limit":   "",
+	}
+
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		runtimeLabels["gomemlimit"] = strconv.FormatInt(limit, 10)
+	}
+
+	// The labels of the unlimited resources are left empty.
+	c := container.Detect()
+	runtimeLabels["container_id"] = c.ID
+
+	if cg := c.Cgroup; cg != nil {
+		runtimeLabels["cgroup_version"] = strconv.Itoa(cg.Version)
+
+		if cg.CPUQuota > 0 {
+			runtimeLabels["cpu_quota"] = strconv.FormatFloat(cg.CPUQuota, 'f', -1, 64)
+		}
+
+		if cg.MemoryLimit > 0 {
+			runtimeLabels["memory_limit"] = strconv.FormatInt(cg.MemoryLimit, 10)
+		}
+	}
+
 	promRuntimeInfo := promauto.NewGauge(
 		prometheus.GaugeOpts{
-			Namespace: "whoami",
-			Subsystem: "runtime",
-			Name:      "info",
-			Help:      "",
-			ConstLabels: map[string]string{
-				"go_version": runtime.Version(),
-				"os":         runtime.GOOS,
-				"arch":       runtime.GOARCH,
-			},
+			Namespace:   "whoami",
+			Subsystem:   "runtime",
+			Name:        "info",
+			Help:        "",
+			ConstLabels: runtimeLabels,
 		})
 
 	prometheus.Unregister(collectors.NewGoCollector())
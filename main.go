@@ -34,8 +34,23 @@ func main() {
 	}
 	slog.SetDefault(l)
 
-	srv := httpserver.NewServer(&httpserver.Config{
+	var tlsServerAddr string
+	if cfg.TLSServerPort != "" {
+		tlsServerAddr = cfg.ServerHost + ":" + cfg.TLSServerPort
+	}
+
+	srv, err := httpserver.NewServer(&httpserver.Config{
 		ServerAddr:         cfg.ServerHost + ":" + cfg.ServerPort,
+		TLSServerAddr:      tlsServerAddr,
+		AdminAddr:          cfg.AdminAddr,
+		GRPCAddr:           cfg.GRPCAddr,
+		TCPEchoAddr:        cfg.TCPEchoAddr,
+		UDPEchoAddr:        cfg.UDPEchoAddr,
+		ProxyProtocol:      cfg.ProxyProtocol,
+		ProxyTrustedCIDRs:  cfg.ProxyTrustedCIDRs,
+		TrustedProxies:     cfg.TrustedProxies,
+		H2C:                cfg.H2C,
+		HTTP3:              cfg.HTTP3,
 		AccessLogEnabled:   cfg.AccessLogEnabled,
 		AccessLogSkipPaths: cfg.AccessLogSkipPaths,
 		ReadTimeout:        cfg.ReadTimeout,
@@ -43,25 +58,40 @@ func main() {
 		WriteTimeout:       cfg.WriteTimeout,
 		TLSCrtFile:         cfg.TLSCrtFile,
 		TLSKeyFile:         cfg.TLSKeyFile,
+		TLSCertPairs:       cfg.TLSCertPairs,
+		TLSCertDir:         cfg.TLSCertDir,
 		TLSCAFile:          cfg.TLSCAFile,
+		TLSClientAuth:      cfg.TLSClientAuth,
+		TLSMinVersion:      cfg.TLSMinVersion,
+		TLSMaxVersion:      cfg.TLSMaxVersion,
+		TLSCipherSuites:    cfg.TLSCipherSuites,
+		TLSCurves:          cfg.TLSCurves,
+		TLSReloadInterval:  cfg.TLSReloadInterval,
+		TLSSelfSigned:      cfg.TLSSelfSigned,
+		TLSSelfSignedSANs:  cfg.TLSSelfSignedSANs,
+		TLSSelfSignedCA:    cfg.TLSSelfSignedCA,
+		MaxBodySize:        cfg.MaxBodySize,
+		HostInfoRefresh:    cfg.HostInfoRefresh,
+		EnvEnabled:         cfg.EnvEnabled,
+		EnvAllow:           cfg.EnvAllow,
+		EnvDeny:            cfg.EnvDeny,
+		EnvRedact:          cfg.EnvRedact,
+		RedactHeaders:      cfg.RedactHeaders,
+		K8sPodInfoDir:      cfg.K8sPodInfoDir,
+		K8sNodeNameEnv:     cfg.K8sNodeNameEnv,
+		K8sPodIPEnv:        cfg.K8sPodIPEnv,
+		K8sZoneEnv:         cfg.K8sZoneEnv,
+		FaultDefaults:      cfg.FaultDefaults,
+		StartupDelay:       cfg.StartupDelay,
+		ShutdownDelay:      cfg.ShutdownDelay,
 	})
+	if err != nil {
+		panic(fmt.Errorf("httpserver.NewServer: %w", err))
+	}
 
 	go func() {
-		slog.Info(fmt.Sprintf("Starting http server on address %s:%s", cfg.ServerHost, cfg.ServerPort))
-
-		if cfg.TLSCrtFile != "" || cfg.TLSKeyFile != "" {
-			slog.Info("TLS enabled")
-
-			if err := srv.StartTLS(); err != nil {
-				slog.Error(fmt.Sprintf("srv.StartTLS: %v", err))
-				os.Exit(1)
-			}
-
-			return
-		}
-
 		if err := srv.Start(); err != nil {
-			slog.Error("srv.Start: %w", err)
+			slog.Error(fmt.Sprintf("srv.Start: %v", err))
 			os.Exit(1)
 		}
 	}()
@@ -73,7 +103,7 @@ func main() {
 
 	slog.Info("Http server graceful shutdown initiated")
 	if err := srv.Shutdown(); err != nil {
-		slog.Error("srv.Shutdown: %w", err)
+		slog.Error(fmt.Sprintf("srv.Shutdown: %v", err))
 		os.Exit(1)
 	}
 }